@@ -57,14 +57,15 @@ func TestWrite(t *testing.T) {
 
 	overwriteDS, err := apiClient.FetchDataSource(ctx, overwriteDataSourceName)
 	c.NoError(err)
-	c.Len(overwriteDS.ConnectionSettings.WebhookConnectionSettings.Columns, 4)
+	c.Len(overwriteDS.ConnectionSettings.WebhookConnectionSettings.Columns, 5)
 	c.Equal("_airbyte_raw_id", overwriteDS.ConnectionSettings.WebhookConnectionSettings.UniqueID)
 
 	dedupDS, err := apiClient.FetchDataSource(ctx, dedupDataSourceName)
 	c.NoError(err)
-	c.Len(dedupDS.ConnectionSettings.WebhookConnectionSettings.Columns, 5)
+	c.Len(dedupDS.ConnectionSettings.WebhookConnectionSettings.Columns, 7)
 	c.Equal([]string{"id"}, dedupDS.ConnectionSettings.WebhookConnectionSettings.TableSettings.OrderBy)
-	c.Equal("updated_at", dedupDS.ConnectionSettings.WebhookConnectionSettings.TableSettings.Engine.ReplacingMergeTreeTableEngine.Ver)
+	// the cursor is nullable, so its version gets a column of its own
+	c.Equal("_airbyte_cursor_version", dedupDS.ConnectionSettings.WebhookConnectionSettings.TableSettings.Engine.ReplacingMergeTreeTableEngine.Ver)
 
 	_, err = client.WaitForState(client.StateChangeOps[models.DataGridResponse]{
 		Pending: []string{"0", "1", "2", "3", "4", "5", "6", "7"}, // Record count before all records are ingested
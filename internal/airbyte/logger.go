@@ -15,6 +15,7 @@ type Logger interface {
 	Catalog(catalog *Catalog)
 	Record(namespace string, stream string, data map[string]any)
 	State(syncState *State)
+	Trace(trace *TraceMessage)
 	Flush()
 }
 
@@ -99,3 +100,10 @@ func (l *logger) State(syncState *State) {
 		State: syncState,
 	})
 }
+
+func (l *logger) Trace(trace *TraceMessage) {
+	l.recordEncoder.Encode(Message{
+		Type:  messageTypeTrace,
+		Trace: trace,
+	})
+}
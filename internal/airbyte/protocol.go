@@ -16,6 +16,7 @@ const (
 	messageTypeSpec             messageType = "SPEC"
 	messageTypeConnectionStatus messageType = "CONNECTION_STATUS"
 	messageTypeLogCatalog       messageType = "CATALOG"
+	messageTypeTrace            messageType = "TRACE"
 )
 
 type Message struct {
@@ -26,6 +27,7 @@ type Message struct {
 	Catalog                *Catalog                `json:"catalog,omitempty"`
 	Record                 *Record                 `json:"record,omitempty"`
 	State                  *State                  `json:"state,omitempty"`
+	Trace                  *TraceMessage           `json:"trace,omitempty"`
 }
 
 // LogLevel defines the log levels that can be emitted with airbyte logs
@@ -45,6 +47,34 @@ type LogMessage struct {
 	Message string   `json:"message"`
 }
 
+// TraceType defines the kinds of trace messages a connector can emit
+type TraceType string
+
+const (
+	TraceTypeError TraceType = "ERROR"
+)
+
+// FailureType tells Airbyte whether an error was caused by the connector or by its configuration
+type FailureType string
+
+const (
+	FailureTypeSystemError FailureType = "system_error"
+	FailureTypeConfigError FailureType = "config_error"
+)
+
+type ErrorTraceMessage struct {
+	Message         string      `json:"message"`
+	InternalMessage string      `json:"internal_message,omitempty"`
+	FailureType     FailureType `json:"failure_type,omitempty"`
+}
+
+// TraceMessage is used to report errors and other sync events to the Airbyte platform
+type TraceMessage struct {
+	Type      TraceType          `json:"type"`
+	EmittedAt float64            `json:"emitted_at"`
+	Error     *ErrorTraceMessage `json:"error,omitempty"`
+}
+
 // DestinationSyncMode represents how the connector should interpret your data
 type DestinationSyncMode string
 
@@ -199,6 +229,8 @@ type PropertyType struct {
 	TypeSet     *PropTypes      `json:"type,omitempty"`
 	AirbyteType AirbytePropType `json:"airbyte_type,omitempty"`
 	Format      FormatType      `json:"format,omitempty"`
+	// Union holds the types of the anyOf and oneOf branches once the schema is resolved.
+	Union []PropertyType `json:"-"`
 }
 
 type PropertySpec struct {
@@ -206,14 +238,28 @@ type PropertySpec struct {
 	Description  string `json:"description"`
 	PropertyType `json:",omitempty"`
 	Examples     []string                `json:"examples,omitempty"`
+	Default      any                     `json:"default,omitempty"`
+	Enum         []any                   `json:"enum,omitempty"`
+	Minimum      *float64                `json:"minimum,omitempty"`
+	Maximum      *float64                `json:"maximum,omitempty"`
+	MultipleOf   *float64                `json:"multipleOf,omitempty"`
+	MaxLength    *int                    `json:"maxLength,omitempty"`
 	Items        map[string]any          `json:"items,omitempty"`
 	Properties   map[string]PropertySpec `json:"properties,omitempty"`
 	IsSecret     bool                    `json:"airbyte_secret,omitempty"`
+	Ref          string                  `json:"$ref,omitempty"`
+	AnyOf        []PropertySpec          `json:"anyOf,omitempty"`
+	OneOf        []PropertySpec          `json:"oneOf,omitempty"`
+	// ResolvedItems holds the schema of the array elements once the schema is resolved.
+	ResolvedItems *PropertySpec `json:"-"`
 }
 
 // Properties defines the property map which is used to define any single "field name" along with its specification
 type Properties struct {
-	Properties map[string]PropertySpec `json:"properties"`
+	Properties  map[string]PropertySpec `json:"properties"`
+	Required    []string                `json:"required,omitempty"`
+	Definitions map[string]PropertySpec `json:"definitions,omitempty"`
+	Defs        map[string]PropertySpec `json:"$defs,omitempty"`
 }
 
 // Stream defines a single "schema" you'd like to sync - think of this as a table, collection, topic, etc. In airbyte terminology these are "streams"
@@ -241,6 +287,7 @@ type ConfiguredStream struct {
 	CursorField         []string            `json:"cursor_field"`
 	DestinationSyncMode DestinationSyncMode `json:"destination_sync_mode"`
 	PrimaryKey          [][]string          `json:"primary_key"`
+	SyncID              int64               `json:"sync_id,omitempty"`
 }
 
 // ConfiguredCatalog is the "selected" schema you want to sync
@@ -249,12 +296,43 @@ type ConfiguredCatalog struct {
 	Streams []ConfiguredStream `json:"streams"`
 }
 
+// RecordChangeType defines how a record field was changed on its way to the destination
+type RecordChangeType string
+
+const (
+	RecordChangeNulled    RecordChangeType = "NULLED"
+	RecordChangeTruncated RecordChangeType = "TRUNCATED"
+)
+
+// RecordChangeReason defines why a record field was changed
+type RecordChangeReason string
+
+const (
+	RecordChangeReasonSourceFieldSizeLimitation      RecordChangeReason = "SOURCE_FIELD_SIZE_LIMITATION"
+	RecordChangeReasonSourceSerializationError       RecordChangeReason = "SOURCE_SERIALIZATION_ERROR"
+	RecordChangeReasonDestinationFieldSizeLimitation RecordChangeReason = "DESTINATION_FIELD_SIZE_LIMITATION"
+	RecordChangeReasonDestinationTypecastError       RecordChangeReason = "DESTINATION_TYPECAST_ERROR"
+)
+
+// RecordChange describes a change made to a record field, so it can be tracked in the destination
+type RecordChange struct {
+	Field  string             `json:"field"`
+	Change RecordChangeType   `json:"change"`
+	Reason RecordChangeReason `json:"reason"`
+}
+
+// RecordMeta holds the information about changes made to a record before it reached the destination
+type RecordMeta struct {
+	Changes []RecordChange `json:"changes"`
+}
+
 // Record defines a record as per airbyte - a "data point"
 type Record struct {
 	Namespace string         `json:"namespace"`
 	Stream    string         `json:"stream"`
 	Data      map[string]any `json:"data"`
 	EmittedAt int64          `json:"emitted_at"`
+	Meta      *RecordMeta    `json:"meta,omitempty"`
 }
 
 // StateStats to emit checkpoints while replicating data
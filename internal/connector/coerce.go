@@ -0,0 +1,450 @@
+package connector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/propeldata/go-client/models"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+const (
+	valueChangeCoerced   = "coerced"
+	valueChangeNulled    = "nulled"
+	valueChangeTruncated = "truncated"
+	valueChangeInvalid   = "invalid"
+)
+
+var (
+	errUnconvertibleValue = errors.New("value can't be converted")
+
+	// maxStringValueBytes keeps single values well below the webhook batch size limit.
+	maxStringValueBytes = 512_000
+
+	// timestampLayouts are the layouts tried, in order, when a string is parsed as a timestamp or a date.
+	timestampLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05.999999999",
+		time.DateOnly,
+	}
+
+	// timeOfDayLayouts are the layouts tried, in order, when a string is parsed as a time of day.
+	timeOfDayLayouts = []string{
+		"15:04:05.999999999Z07:00",
+		"15:04:05.999999999",
+		"15:04Z07:00",
+		"15:04",
+	}
+
+	integerRanges = map[models.PropelType][2]float64{
+		models.Int8PropelType:  {math.MinInt8, math.MaxInt8},
+		models.Int16PropelType: {math.MinInt16, math.MaxInt16},
+		models.Int32PropelType: {math.MinInt32, math.MaxInt32},
+		models.Int64PropelType: {math.MinInt64, math.MaxInt64},
+	}
+)
+
+// valueChange describes how the coercion changed a record value.
+type valueChange struct {
+	// field is the record property the value belongs to.
+	field string
+	// change is either coerced, nulled, truncated or invalid. Invalid values could not be converted and were left as is.
+	change string
+	// reason explains the change.
+	reason string
+}
+
+// recordCoercer normalizes record values to the types of the Data Source columns they are written to.
+type recordCoercer struct {
+	columns map[string]models.WebhookColumn
+	// timeOfDayFields are the stream properties holding times of day.
+	timeOfDayFields map[string]bool
+	// location is the timezone of timestamps without an offset.
+	location *time.Location
+	// changeCounts counts the changes per field and kind of change, to report them once the sync ends.
+	changeCounts map[string]map[string]int
+}
+
+func newRecordCoercer(dataSource *models.DataSource, configuredStream airbyte.ConfiguredStream, location *time.Location) *recordCoercer {
+	columns := make(map[string]models.WebhookColumn, len(dataSource.ConnectionSettings.WebhookConnectionSettings.Columns))
+	for _, column := range dataSource.ConnectionSettings.WebhookConnectionSettings.Columns {
+		columns[column.JsonProperty] = column
+	}
+
+	timeOfDayFields := map[string]bool{}
+	for propertyName, propertySpec := range configuredStream.Stream.JSONSchema.Properties {
+		if isTimeOfDay(propertySpec.PropertyType) {
+			timeOfDayFields[propertyName] = true
+		}
+	}
+
+	return &recordCoercer{
+		columns:         columns,
+		timeOfDayFields: timeOfDayFields,
+		location:        location,
+		changeCounts:    map[string]map[string]int{},
+	}
+}
+
+// coerce converts the record values in place and returns the changes made. Values that can't be converted are
+// nulled when their column is nullable, and left untouched otherwise so that Propel reports them.
+func (rc *recordCoercer) coerce(recordMap map[string]any) []valueChange {
+	var changes []valueChange
+
+	for field, value := range recordMap {
+		column, ok := rc.columns[field]
+		if !ok || value == nil {
+			continue
+		}
+
+		var coerced any
+		var err error
+		if rc.timeOfDayFields[field] {
+			coerced, err = coerceTimeOfDay(value, column.Type)
+		} else {
+			coerced, err = coerceValue(value, column.Type, rc.location)
+		}
+		if err != nil {
+			change := valueChange{field: field, change: valueChangeInvalid, reason: fmt.Sprintf("%v to %s", err, column.Type.String())}
+			if column.Nullable {
+				recordMap[field] = nil
+				change.change = valueChangeNulled
+			}
+
+			changes = append(changes, change)
+			continue
+		}
+
+		if !reflect.DeepEqual(value, coerced) {
+			recordMap[field] = coerced
+			changes = append(changes, valueChange{field: field, change: valueChangeCoerced, reason: fmt.Sprintf("converted to %s", column.Type.String())})
+		}
+
+		if stringValue, ok := coerced.(string); ok && len(stringValue) > maxStringValueBytes {
+			recordMap[field] = truncateString(stringValue, maxStringValueBytes)
+			changes = append(changes, valueChange{field: field, change: valueChangeTruncated, reason: fmt.Sprintf("longer than %d bytes", maxStringValueBytes)})
+		}
+	}
+
+	for _, change := range changes {
+		if rc.changeCounts[change.field] == nil {
+			rc.changeCounts[change.field] = map[string]int{}
+		}
+
+		rc.changeCounts[change.field][change.change]++
+	}
+
+	return changes
+}
+
+// hasColumn reports whether the Data Source has a column for the record property.
+func (rc *recordCoercer) hasColumn(field string) bool {
+	_, ok := rc.columns[field]
+	return ok
+}
+
+// missingValue returns the first non-nullable column, by name, the record has no value for.
+func (rc *recordCoercer) missingValue(recordMap map[string]any) (string, bool) {
+	missing := make([]string, 0)
+	for field, column := range rc.columns {
+		if !column.Nullable && recordMap[field] == nil {
+			missing = append(missing, column.Name)
+		}
+	}
+
+	if len(missing) == 0 {
+		return "", false
+	}
+
+	sort.Strings(missing)
+
+	return missing[0], true
+}
+
+// report returns a summary of the changes made, one line per field and kind of change.
+func (rc *recordCoercer) report() []string {
+	lines := make([]string, 0, len(rc.changeCounts))
+	for field, counts := range rc.changeCounts {
+		for change, count := range counts {
+			lines = append(lines, fmt.Sprintf("%d values of %q %s", count, field, change))
+		}
+	}
+
+	sort.Strings(lines)
+
+	return lines
+}
+
+// coerceValue converts a JSON decoded value into the representation Propel expects for the column type.
+// Timestamps without an offset are read in the given location, and all timestamps are sent in UTC.
+func coerceValue(value any, columnType models.PropelType, location *time.Location) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if intValue, ok := value.(int); ok {
+		value = int64(intValue)
+	}
+
+	switch columnType {
+	case models.BooleanPropelType:
+		return coerceBoolean(value)
+	case models.Int8PropelType, models.Int16PropelType, models.Int32PropelType, models.Int64PropelType:
+		return coerceInteger(value, integerRanges[columnType])
+	case models.FloatPropelType, models.DoublePropelType:
+		return coerceFloat(value)
+	case models.DatePropelType:
+		// dates are calendar days, so timestamps keep the day of their own offset rather than shifting to that of UTC
+		t, err := coerceTime(value, time.UTC)
+		if err != nil {
+			return nil, err
+		}
+
+		return t.Format(time.DateOnly), nil
+	case models.TimestampPropelType:
+		t, err := coerceTime(value, location)
+		if err != nil {
+			return nil, err
+		}
+
+		return t.UTC().Format(time.RFC3339Nano), nil
+	case models.StringPropelType:
+		return coerceString(value)
+	}
+
+	return value, nil
+}
+
+func coerceBoolean(value any) (any, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64, int64:
+		switch v {
+		case float64(0), int64(0):
+			return false, nil
+		case float64(1), int64(1):
+			return true, nil
+		}
+	case json.Number:
+		if floatValue, err := v.Float64(); err == nil {
+			return coerceBoolean(floatValue)
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "0", "f", "false", "off", "n", "no":
+			return false, nil
+		case "1", "t", "true", "on", "y", "yes":
+			return true, nil
+		}
+	}
+
+	return nil, errUnconvertibleValue
+}
+
+func coerceInteger(value any, bounds [2]float64) (any, error) {
+	var number float64
+
+	switch v := value.(type) {
+	case float64:
+		number = v
+	case int64:
+		if float64(v) < bounds[0] || float64(v) > bounds[1] {
+			return nil, errUnconvertibleValue
+		}
+
+		return v, nil
+	case json.Number:
+		if intValue, err := v.Int64(); err == nil {
+			if float64(intValue) < bounds[0] || float64(intValue) > bounds[1] {
+				return nil, errUnconvertibleValue
+			}
+
+			return v, nil
+		}
+
+		floatValue, err := v.Float64()
+		if err != nil {
+			return nil, errUnconvertibleValue
+		}
+
+		number = floatValue
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+
+		return int64(0), nil
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if intValue, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+			number = float64(intValue)
+			if number >= bounds[0] && number <= bounds[1] {
+				return intValue, nil
+			}
+
+			return nil, errUnconvertibleValue
+		}
+
+		floatValue, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return nil, errUnconvertibleValue
+		}
+
+		number = floatValue
+	default:
+		return nil, errUnconvertibleValue
+	}
+
+	// the upper bound is checked against -min, as the max of INT64 rounds up to 2^63 once converted to float64
+	if number != math.Trunc(number) || number < bounds[0] || number >= -bounds[0] {
+		return nil, errUnconvertibleValue
+	}
+
+	if _, ok := value.(float64); ok {
+		// JSON numbers are already valid integers, keep them untouched
+		return value, nil
+	}
+
+	return int64(number), nil
+}
+
+func coerceFloat(value any) (any, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		floatValue, err := v.Float64()
+		if err != nil || math.IsInf(floatValue, 0) {
+			return nil, errUnconvertibleValue
+		}
+
+		// the number is sent as is, so that Propel rounds it once
+		return v, nil
+	case bool:
+		if v {
+			return float64(1), nil
+		}
+
+		return float64(0), nil
+	case string:
+		floatValue, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(floatValue) || math.IsInf(floatValue, 0) {
+			return nil, errUnconvertibleValue
+		}
+
+		return floatValue, nil
+	}
+
+	return nil, errUnconvertibleValue
+}
+
+// coerceTime parses timestamp strings and Unix epochs. Strings without an offset are read in the given location, and
+// strings with one keep it, while epochs are in UTC. The epoch unit is inferred from its magnitude, so that seconds, milliseconds, microseconds and nanoseconds since
+// the epoch are all supported.
+func coerceTime(value any, location *time.Location) (time.Time, error) {
+	switch v := value.(type) {
+	case float64:
+		return epochToTime(v), nil
+	case int64:
+		return epochToTime(float64(v)), nil
+	case json.Number:
+		if epoch, err := v.Float64(); err == nil {
+			return epochToTime(epoch), nil
+		}
+	case string:
+		trimmed := strings.TrimSpace(v)
+		for _, layout := range timestampLayouts {
+			if t, err := time.ParseInLocation(layout, trimmed, location); err == nil {
+				return t, nil
+			}
+		}
+
+		if epoch, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return epochToTime(epoch), nil
+		}
+	}
+
+	return time.Time{}, errUnconvertibleValue
+}
+
+func epochToTime(epoch float64) time.Time {
+	switch absEpoch := math.Abs(epoch); {
+	case absEpoch < 1e11:
+		return time.UnixMicro(int64(epoch * 1e6)).UTC()
+	case absEpoch < 1e14:
+		return time.UnixMicro(int64(epoch * 1e3)).UTC()
+	case absEpoch < 1e17:
+		return time.UnixMicro(int64(epoch)).UTC()
+	default:
+		return time.Unix(0, int64(epoch)).UTC()
+	}
+}
+
+// coerceTimeOfDay converts time-of-day strings into seconds since midnight when stored in integer columns.
+// Times with an offset are converted to UTC first. Any other column type gets the value as is.
+func coerceTimeOfDay(value any, columnType models.PropelType) (any, error) {
+	bounds, isInteger := integerRanges[columnType]
+	stringValue, isString := value.(string)
+	if !isInteger || !isString {
+		return coerceValue(value, columnType, time.UTC)
+	}
+
+	trimmed := strings.TrimSpace(stringValue)
+	for _, layout := range timeOfDayLayouts {
+		if t, err := time.Parse(layout, trimmed); err == nil {
+			t = t.UTC()
+			return coerceInteger(int64(t.Hour()*3600+t.Minute()*60+t.Second()), bounds)
+		}
+	}
+
+	return coerceInteger(value, bounds)
+}
+
+func coerceString(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+
+	valueJsonEncoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, errUnconvertibleValue
+	}
+
+	return string(valueJsonEncoded), nil
+}
+
+// truncateString shortens the string to at most maxBytes without splitting a UTF-8 character.
+func truncateString(value string, maxBytes int) string {
+	if len(value) <= maxBytes {
+		return value
+	}
+
+	for maxBytes > 0 && !utf8.RuneStart(value[maxBytes]) {
+		maxBytes--
+	}
+
+	return value[:maxBytes]
+}
@@ -0,0 +1,208 @@
+package connector
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         any
+		columnType    models.PropelType
+		location      string
+		expectedValue any
+		expectedError bool
+	}{
+		{name: "Null value", value: nil, columnType: models.Int64PropelType, expectedValue: nil},
+		{name: "Boolean string", value: "yes", columnType: models.BooleanPropelType, expectedValue: true},
+		{name: "Boolean number", value: float64(0), columnType: models.BooleanPropelType, expectedValue: false},
+		{name: "Invalid boolean", value: "maybe", columnType: models.BooleanPropelType, expectedError: true},
+		{name: "Integer string", value: "42", columnType: models.Int64PropelType, expectedValue: int64(42)},
+		{name: "Integer number", value: float64(42), columnType: models.Int64PropelType, expectedValue: float64(42)},
+		{name: "Integral float string", value: "42.0", columnType: models.Int32PropelType, expectedValue: int64(42)},
+		{name: "Fractional integer", value: float64(4.2), columnType: models.Int64PropelType, expectedError: true},
+		{name: "Integer out of range", value: float64(300), columnType: models.Int8PropelType, expectedError: true},
+		{name: "Integer JSON number", value: json.Number("42"), columnType: models.Int64PropelType, expectedValue: json.Number("42")},
+		{name: "Integral exponent JSON number", value: json.Number("4.2e1"), columnType: models.Int32PropelType, expectedValue: int64(42)},
+		{name: "Integer JSON number out of range", value: json.Number("9223372036854775808"), columnType: models.Int64PropelType, expectedError: true},
+		{name: "Double JSON number", value: json.Number("4.2"), columnType: models.DoublePropelType, expectedValue: json.Number("4.2")},
+		{name: "Big integer to string", value: json.Number("123456789012345678901234567890"), columnType: models.StringPropelType, expectedValue: "123456789012345678901234567890"},
+		{name: "Big number to string", value: json.Number("1234567890.123456789012345"), columnType: models.StringPropelType, expectedValue: "1234567890.123456789012345"},
+		{name: "Timestamp from JSON number", value: json.Number("1705379796"), columnType: models.TimestampPropelType, expectedValue: "2024-01-16T04:36:36Z"},
+		{name: "Double string", value: "4.2", columnType: models.DoublePropelType, expectedValue: 4.2},
+		{name: "Invalid double", value: "four", columnType: models.DoublePropelType, expectedError: true},
+		{name: "Timestamp from epoch seconds", value: float64(1705379796), columnType: models.TimestampPropelType, expectedValue: "2024-01-16T04:36:36Z"},
+		{name: "Timestamp from epoch milliseconds", value: int64(1705379796123), columnType: models.TimestampPropelType, expectedValue: "2024-01-16T04:36:36.123Z"},
+		{name: "Timestamp with offset", value: "2024-01-16T06:36:36+02:00", columnType: models.TimestampPropelType, expectedValue: "2024-01-16T04:36:36Z"},
+		{name: "Timestamp without T", value: "2024-01-16 04:36:36", columnType: models.TimestampPropelType, expectedValue: "2024-01-16T04:36:36Z"},
+		{name: "Timestamp without offset in source timezone", value: "2024-01-16 04:36:36", columnType: models.TimestampPropelType, location: "America/New_York", expectedValue: "2024-01-16T09:36:36Z"},
+		{name: "Timestamp with offset ignores source timezone", value: "2024-01-16T04:36:36Z", columnType: models.TimestampPropelType, location: "America/New_York", expectedValue: "2024-01-16T04:36:36Z"},
+		{name: "Timestamp from epoch ignores source timezone", value: float64(1705379796), columnType: models.TimestampPropelType, location: "Asia/Tokyo", expectedValue: "2024-01-16T04:36:36Z"},
+		{name: "Date ignores source timezone", value: "2024-01-16", columnType: models.DatePropelType, location: "Asia/Tokyo", expectedValue: "2024-01-16"},
+		{name: "Invalid timestamp", value: "yesterday", columnType: models.TimestampPropelType, expectedError: true},
+		{name: "Date from timestamp", value: "2024-01-16T04:36:36Z", columnType: models.DatePropelType, expectedValue: "2024-01-16"},
+		{name: "Date from timestamp keeps its offset", value: "2024-01-01T23:00:00-05:00", columnType: models.DatePropelType, expectedValue: "2024-01-01"},
+		{name: "String from number", value: float64(12345678901), columnType: models.StringPropelType, expectedValue: "12345678901"},
+		{name: "String from object", value: map[string]any{"a": float64(1)}, columnType: models.StringPropelType, expectedValue: `{"a":1}`},
+		{name: "JSON value", value: []any{"a"}, columnType: models.JsonPropelType, expectedValue: []any{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			a := assert.New(st)
+
+			location := time.UTC
+			if tt.location != "" {
+				location, _ = time.LoadLocation(tt.location)
+			}
+
+			value, err := coerceValue(tt.value, tt.columnType, location)
+			if tt.expectedError {
+				a.Error(err)
+				return
+			}
+
+			a.NoError(err)
+			a.Equal(tt.expectedValue, value)
+		})
+	}
+}
+
+func TestRecordCoercer_Coerce(t *testing.T) {
+	a := assert.New(t)
+
+	coercer := newRecordCoercer(&models.DataSource{
+		ConnectionSettings: models.ConnectionSettings{
+			WebhookConnectionSettings: models.WebhookConnectionSettings{
+				Columns: []models.WebhookColumn{
+					{Name: "id", Type: models.Int64PropelType, JsonProperty: "id"},
+					{Name: "price", Type: models.DoublePropelType, Nullable: true, JsonProperty: "price"},
+					{Name: "active", Type: models.BooleanPropelType, Nullable: true, JsonProperty: "active"},
+					{Name: "name", Type: models.StringPropelType, Nullable: true, JsonProperty: "name"},
+				},
+			},
+		},
+	}, airbyte.ConfiguredStream{}, time.UTC)
+
+	record := map[string]any{"id": "not an id", "price": "free", "active": "true", "name": "tacos", "extra": "value"}
+	changes := coercer.coerce(record)
+
+	a.Len(changes, 3)
+	a.Equal(map[string]any{"id": "not an id", "price": nil, "active": true, "name": "tacos", "extra": "value"}, record)
+	a.Equal([]string{
+		`1 values of "active" coerced`,
+		`1 values of "id" invalid`,
+		`1 values of "price" nulled`,
+	}, coercer.report())
+}
+
+func TestRecordCoercer_Coerce_Truncate(t *testing.T) {
+	a := assert.New(t)
+
+	defer func(maxBytes int) { maxStringValueBytes = maxBytes }(maxStringValueBytes)
+	maxStringValueBytes = 5
+
+	coercer := newRecordCoercer(&models.DataSource{
+		ConnectionSettings: models.ConnectionSettings{
+			WebhookConnectionSettings: models.WebhookConnectionSettings{
+				Columns: []models.WebhookColumn{
+					{Name: "name", Type: models.StringPropelType, Nullable: true, JsonProperty: "name"},
+				},
+			},
+		},
+	}, airbyte.ConfiguredStream{}, time.UTC)
+
+	record := map[string]any{"name": "jalapeño"}
+	changes := coercer.coerce(record)
+
+	a.Equal([]valueChange{{field: "name", change: valueChangeTruncated, reason: "longer than 5 bytes"}}, changes)
+	a.Equal(map[string]any{"name": "jalap"}, record)
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		maxBytes int
+		expected string
+	}{
+		{name: "Short string", value: "taco", maxBytes: 10, expected: "taco"},
+		{name: "ASCII string", value: "burrito", maxBytes: 4, expected: "burr"},
+		{name: "Multi-byte character", value: "señor", maxBytes: 3, expected: "se"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expected, truncateString(tt.value, tt.maxBytes))
+		})
+	}
+}
+
+func TestCoerceTimeOfDay(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         any
+		columnType    models.PropelType
+		expectedValue any
+		expectedError bool
+	}{
+		{name: "String column", value: "12:34:56+02:00", columnType: models.StringPropelType, expectedValue: "12:34:56+02:00"},
+		{name: "Time without offset", value: "12:34:56", columnType: models.Int32PropelType, expectedValue: int64(45296)},
+		{name: "Time with fraction", value: "12:34:56.789", columnType: models.Int32PropelType, expectedValue: int64(45296)},
+		{name: "Time with offset", value: "12:34:56+02:00", columnType: models.Int32PropelType, expectedValue: int64(38096)},
+		{name: "Time with offset before midnight UTC", value: "00:30:00+02:00", columnType: models.Int32PropelType, expectedValue: int64(81000)},
+		{name: "Time in UTC", value: "12:34Z", columnType: models.Int32PropelType, expectedValue: int64(45240)},
+		{name: "Seconds since midnight", value: float64(45296), columnType: models.Int32PropelType, expectedValue: float64(45296)},
+		{name: "Invalid time", value: "noon", columnType: models.Int32PropelType, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			a := assert.New(st)
+
+			value, err := coerceTimeOfDay(tt.value, tt.columnType)
+			if tt.expectedError {
+				a.Error(err)
+				return
+			}
+
+			a.NoError(err)
+			a.Equal(tt.expectedValue, value)
+		})
+	}
+}
+
+func TestRecordCoercer_Coerce_TimeOfDay(t *testing.T) {
+	a := assert.New(t)
+
+	coercer := newRecordCoercer(&models.DataSource{
+		ConnectionSettings: models.ConnectionSettings{
+			WebhookConnectionSettings: models.WebhookConnectionSettings{
+				Columns: []models.WebhookColumn{
+					{Name: "opens_at", Type: models.Int32PropelType, Nullable: true, JsonProperty: "opens_at"},
+					{Name: "ordered_at", Type: models.TimestampPropelType, Nullable: true, JsonProperty: "ordered_at"},
+				},
+			},
+		},
+	}, airbyte.ConfiguredStream{
+		Stream: airbyte.Stream{
+			JSONSchema: airbyte.Properties{
+				Properties: map[string]airbyte.PropertySpec{
+					"opens_at": {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}, AirbyteType: airbyte.TimeWOTZ}},
+				},
+			},
+		},
+	}, time.FixedZone("UTC+2", 2*60*60))
+
+	record := map[string]any{"opens_at": "09:00:00", "ordered_at": "2024-01-16T09:00:00"}
+	coercer.coerce(record)
+
+	a.Equal(map[string]any{"opens_at": int64(32400), "ordered_at": "2024-01-16T07:00:00Z"}, record)
+}
@@ -0,0 +1,156 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/propeldata/go-client/models"
+)
+
+// maxColumnNameLength is the length Data Source column names are truncated to.
+const maxColumnNameLength = 64
+
+// reservedColumnNames are SQL keywords that can't be used as column names as is. They get a trailing underscore.
+var reservedColumnNames = []string{
+	"all", "and", "as", "asc", "between", "by", "case", "desc", "distinct", "else", "end", "from", "group", "having",
+	"in", "is", "join", "like", "limit", "not", "null", "on", "or", "order", "select", "then", "union", "when",
+	"where", "with",
+}
+
+// columnNames returns the Data Source column name of each property. Properties keep the columns the existing Data
+// Source holds them in, keyed by property, unless renamed to another column. Renamed properties get their configured
+// names, other names are sanitized, and names colliding with another column regardless of case get a numeric suffix.
+func columnNames(propertyNames []string, renames map[string]string, existing map[string]string) map[string]string {
+	taken := map[string]bool{strings.ToLower(airbyteCursorVersionColumn): true}
+	for _, column := range defaultAirbyteColumns {
+		taken[strings.ToLower(column.Name)] = true
+	}
+
+	for _, metadataColumn := range metadataColumns {
+		taken[strings.ToLower(metadataColumn.column.Name)] = true
+	}
+
+	// the columns of the existing Data Source are never claimed by other properties, even those of removed properties
+	for _, columnName := range existing {
+		taken[strings.ToLower(columnName)] = true
+	}
+
+	// renamed properties claim their names first, then properties whose names need no sanitizing
+	rank := func(propertyName string) int {
+		if _, ok := renames[propertyName]; ok {
+			return 0
+		}
+
+		if sanitizeColumnName(propertyName) == propertyName {
+			return 1
+		}
+
+		return 2
+	}
+
+	sorted := slices.Clone(propertyNames)
+	slices.Sort(sorted)
+	slices.SortStableFunc(sorted, func(a, b string) int { return rank(a) - rank(b) })
+
+	names := make(map[string]string, len(propertyNames))
+	for _, propertyName := range sorted {
+		columnName, renamed := renames[propertyName]
+		if !renamed {
+			columnName = sanitizeColumnName(propertyName)
+		}
+
+		if existingName, ok := existing[propertyName]; ok && (!renamed || existingName == columnName) {
+			names[propertyName] = existingName
+			continue
+		}
+
+		name := columnName
+		for i := 2; taken[strings.ToLower(name)]; i++ {
+			suffix := fmt.Sprintf("_%d", i)
+			name = truncateColumnName(columnName, maxColumnNameLength-len(suffix)) + suffix
+		}
+
+		taken[strings.ToLower(name)] = true
+		names[propertyName] = name
+	}
+
+	return names
+}
+
+// existingColumnNames returns the column of each property the existing Data Source holds, leaving out the Airbyte
+// columns. It returns nil when there is no Data Source yet.
+func existingColumnNames(dataSource *models.DataSource) map[string]string {
+	if dataSource == nil {
+		return nil
+	}
+
+	names := map[string]string{}
+	for property, column := range columnsByProperty(dataSource) {
+		if column.Name != airbyteCursorVersionColumn && !isAirbyteColumn(column.Name) {
+			names[property] = column.Name
+		}
+	}
+
+	return names
+}
+
+// columnsByProperty returns the columns of the Data Source keyed by the JSON property they are filled from, or by
+// their name when they have none.
+func columnsByProperty(dataSource *models.DataSource) map[string]models.WebhookColumn {
+	columns := make(map[string]models.WebhookColumn, len(dataSource.ConnectionSettings.WebhookConnectionSettings.Columns))
+	for _, column := range dataSource.ConnectionSettings.WebhookConnectionSettings.Columns {
+		property := column.JsonProperty
+		if property == "" {
+			property = column.Name
+		}
+
+		columns[property] = column
+	}
+
+	return columns
+}
+
+// sanitizeColumnName replaces every character other than ASCII letters, digits and underscores by an underscore,
+// prefixes names starting with a digit and suffixes reserved words by an underscore, and truncates long names.
+func sanitizeColumnName(propertyName string) string {
+	var builder strings.Builder
+	for _, r := range propertyName {
+		if r == '_' || r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune('_')
+		}
+	}
+
+	name := builder.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "_" + name
+	}
+
+	if slices.Contains(reservedColumnNames, strings.ToLower(name)) {
+		name += "_"
+	}
+
+	return truncateColumnName(name, maxColumnNameLength)
+}
+
+// truncateColumnName truncates the column name to maxLength bytes. Sanitized names are ASCII only.
+func truncateColumnName(name string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+
+	return name[:maxLength]
+}
+
+// orderRank returns the position of the property in the configured column order, or the length of the order for
+// properties it doesn't list.
+func orderRank(order []string, propertyName string) int {
+	if index := slices.Index(order, propertyName); index >= 0 {
+		return index
+	}
+
+	return len(order)
+}
@@ -0,0 +1,122 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeColumnName(t *testing.T) {
+	tests := []struct {
+		name         string
+		propertyName string
+		expected     string
+	}{
+		{name: "Valid name", propertyName: "order_id", expected: "order_id"},
+		{name: "Spaces and dots", propertyName: "customer.first name", expected: "customer_first_name"},
+		{name: "Leading digit", propertyName: "1st_order", expected: "_1st_order"},
+		{name: "Reserved word", propertyName: "Select", expected: "Select_"},
+		{name: "Non-ASCII letters", propertyName: "señor", expected: "se_or"},
+		{name: "Empty name", propertyName: "", expected: "_"},
+		{name: "Long name", propertyName: strings.Repeat("a", 100), expected: strings.Repeat("a", maxColumnNameLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expected, sanitizeColumnName(tt.propertyName))
+		})
+	}
+}
+
+func TestColumnNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		propertyNames []string
+		renames       map[string]string
+		existing      map[string]string
+		expected      map[string]string
+	}{
+		{
+			name:          "No collisions",
+			propertyNames: []string{"id", "order date"},
+			expected:      map[string]string{"id": "id", "order date": "order_date"},
+		},
+		{
+			name:          "Valid names are kept",
+			propertyNames: []string{"order date", "order_date"},
+			expected:      map[string]string{"order date": "order_date_2", "order_date": "order_date"},
+		},
+		{
+			name:          "Names differing in case",
+			propertyNames: []string{"foo", "Foo", "FOO"},
+			expected:      map[string]string{"FOO": "FOO", "Foo": "Foo_2", "foo": "foo_3"},
+		},
+		{
+			name:          "Renamed properties",
+			propertyNames: []string{"customer_id", "cust id", "id"},
+			renames:       map[string]string{"cust id": "customer_id", "id": "order_id"},
+			expected:      map[string]string{"cust id": "customer_id", "customer_id": "customer_id_2", "id": "order_id"},
+		},
+		{
+			name:          "Existing columns",
+			propertyNames: []string{"order date", "order_date", "order-date"},
+			existing:      map[string]string{"order date": "order_date", "order-date": "order_date_2"},
+			expected:      map[string]string{"order date": "order_date", "order-date": "order_date_2", "order_date": "order_date_3"},
+		},
+		{
+			name:          "Existing columns of removed properties",
+			propertyNames: []string{"order_date"},
+			existing:      map[string]string{"order date": "order_date"},
+			expected:      map[string]string{"order_date": "order_date_2"},
+		},
+		{
+			name:          "Existing column renamed",
+			propertyNames: []string{"id", "customer_id"},
+			renames:       map[string]string{"id": "order_id", "customer_id": "customer_id"},
+			existing:      map[string]string{"id": "id", "customer_id": "customer_id"},
+			expected:      map[string]string{"id": "order_id", "customer_id": "customer_id"},
+		},
+		{
+			name:          "Airbyte columns",
+			propertyNames: []string{"_airbyte_raw_id", "_AIRBYTE_META"},
+			expected:      map[string]string{"_airbyte_raw_id": "_airbyte_raw_id_2", "_AIRBYTE_META": "_AIRBYTE_META_2"},
+		},
+		{
+			name:          "Long names",
+			propertyNames: []string{strings.Repeat("a", 100), strings.Repeat("a", 101)},
+			expected: map[string]string{
+				strings.Repeat("a", 100): strings.Repeat("a", maxColumnNameLength),
+				strings.Repeat("a", 101): strings.Repeat("a", maxColumnNameLength-2) + "_2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expected, columnNames(tt.propertyNames, tt.renames, tt.existing))
+		})
+	}
+}
+
+func TestExistingColumnNames(t *testing.T) {
+	a := assert.New(t)
+
+	a.Nil(existingColumnNames(nil))
+
+	dataSource := &models.DataSource{
+		ConnectionSettings: models.ConnectionSettings{
+			WebhookConnectionSettings: models.WebhookConnectionSettings{
+				Columns: []models.WebhookColumn{
+					{Name: "order_date_2", Type: models.DatePropelType, JsonProperty: "order date"},
+					{Name: "legacy", Type: models.StringPropelType},
+					{Name: airbyteRawIdColumn, Type: models.StringPropelType, JsonProperty: airbyteRawIdColumn},
+					{Name: airbyteCursorVersionColumn, Type: models.Int64PropelType, JsonProperty: airbyteCursorVersionColumn},
+				},
+			},
+		},
+	}
+
+	a.Equal(map[string]string{"order date": "order_date_2", "legacy": "legacy"}, existingColumnNames(dataSource))
+}
@@ -0,0 +1,122 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/propeldata/go-client"
+	"github.com/propeldata/go-client/models"
+)
+
+// checkDataSourceCompatibility compares an existing Data Source with the settings its configured stream would
+// create it with, and returns every mismatch found. Columns are matched by the property they are filled from. Only
+// missing key columns and columns whose property is now stored under another name are mismatches, since schema
+// evolution can add any other column to the Data Source, while columns can't be renamed.
+func checkDataSourceCompatibility(dataSource *models.DataSource, createDataSourceOpts client.CreateDataSourceOpts) []string {
+	settings := dataSource.ConnectionSettings.WebhookConnectionSettings
+	mismatches := make([]string, 0)
+
+	var expectedUniqueID string
+	if createDataSourceOpts.UniqueID != nil {
+		expectedUniqueID = *createDataSourceOpts.UniqueID
+	}
+
+	if settings.UniqueID != expectedUniqueID {
+		mismatches = append(mismatches, fmt.Sprintf("unique ID is %q, expected %q", settings.UniqueID, expectedUniqueID))
+	}
+
+	if createDataSourceOpts.TableSettings != nil {
+		var orderBy []string
+		var ver string
+		if settings.TableSettings != nil {
+			orderBy = settings.TableSettings.OrderBy
+			if settings.TableSettings.Engine != nil {
+				ver = settings.TableSettings.Engine.ReplacingMergeTreeTableEngine.Ver
+			}
+		}
+
+		if !slices.Equal(orderBy, createDataSourceOpts.TableSettings.OrderBy) {
+			mismatches = append(mismatches, fmt.Sprintf("primary key changed: ORDER BY is %v, expected %v", orderBy, createDataSourceOpts.TableSettings.OrderBy))
+		}
+
+		expectedVer := createDataSourceOpts.TableSettings.Engine.ReplacingMergeTree.Ver
+		if ver != expectedVer {
+			mismatches = append(mismatches, fmt.Sprintf("cursor changed: version column is %q, expected %q", ver, expectedVer))
+		}
+	}
+
+	existingColumns := columnsByProperty(dataSource)
+	for _, column := range createDataSourceOpts.Columns {
+		existing, ok := existingColumns[column.JsonProperty]
+		if ok && existing.Name != column.Name {
+			mismatches = append(mismatches, fmt.Sprintf("property %q is stored in column %q, expected %q", column.JsonProperty, existing.Name, column.Name))
+			continue
+		}
+
+		if !ok && !column.Nullable && isKeyColumn(column.Name, createDataSourceOpts) {
+			mismatches = append(mismatches, fmt.Sprintf("column %q is missing", column.Name))
+		}
+	}
+
+	return mismatches
+}
+
+// isKeyColumn reports whether the column identifies, orders or versions the records of the Data Source, or is one of
+// the columns the connector always sets.
+func isKeyColumn(columnName string, createDataSourceOpts client.CreateDataSourceOpts) bool {
+	if slices.ContainsFunc(defaultAirbyteColumns, func(column *models.WebhookDataSourceColumnInput) bool { return column.Name == columnName }) {
+		return true
+	}
+
+	if createDataSourceOpts.UniqueID != nil && *createDataSourceOpts.UniqueID == columnName {
+		return true
+	}
+
+	if createDataSourceOpts.Timestamp != nil && *createDataSourceOpts.Timestamp == columnName {
+		return true
+	}
+
+	tableSettings := createDataSourceOpts.TableSettings
+
+	return tableSettings != nil && (slices.Contains(tableSettings.OrderBy, columnName) || tableSettings.Engine.ReplacingMergeTree.Ver == columnName)
+}
+
+// checkManagedDataSourceColumns compares the columns of a user-managed Data Source with those its configured stream
+// would create, and returns every stream property the Data Source can't receive, along with every non-nullable
+// column no property fills. Columns are matched by their JSON property.
+func checkManagedDataSourceColumns(dataSource *models.DataSource, createDataSourceOpts client.CreateDataSourceOpts) []string {
+	existingColumns := map[string]models.WebhookColumn{}
+	for _, column := range dataSource.ConnectionSettings.WebhookConnectionSettings.Columns {
+		existingColumns[column.JsonProperty] = column
+	}
+
+	mismatches := make([]string, 0)
+	jsonProperties := map[string]bool{}
+
+	for _, column := range createDataSourceOpts.Columns {
+		jsonProperties[column.JsonProperty] = true
+
+		// the Airbyte columns are only filled when the Data Source has them, as its table settings are the user's own
+		if column.Name == airbyteCursorVersionColumn || isAirbyteColumn(column.Name) {
+			continue
+		}
+
+		existing, ok := existingColumns[column.JsonProperty]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("property %q has no column", column.JsonProperty))
+			continue
+		}
+
+		if !canHoldPropelType(existing.Type, column.Type) {
+			mismatches = append(mismatches, fmt.Sprintf("column %q of type %s can't hold property %q of type %s", existing.Name, existing.Type.String(), column.JsonProperty, column.Type.String()))
+		}
+	}
+
+	for _, column := range dataSource.ConnectionSettings.WebhookConnectionSettings.Columns {
+		if !column.Nullable && !jsonProperties[column.JsonProperty] {
+			mismatches = append(mismatches, fmt.Sprintf("column %q is not nullable but no property fills it", column.Name))
+		}
+	}
+
+	return mismatches
+}
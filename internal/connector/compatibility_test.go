@@ -0,0 +1,69 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/propeldata/go-client"
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckManagedDataSourceColumns(t *testing.T) {
+	dataSource := &models.DataSource{
+		ConnectionSettings: models.ConnectionSettings{
+			WebhookConnectionSettings: models.WebhookConnectionSettings{
+				Columns: []models.WebhookColumn{
+					{Name: "ID", Type: models.Int64PropelType, JsonProperty: "id"},
+					{Name: "price", Type: models.Int32PropelType, Nullable: true, JsonProperty: "price"},
+					{Name: "notes", Type: models.StringPropelType, Nullable: true, JsonProperty: "notes"},
+					{Name: "tenant", Type: models.StringPropelType, JsonProperty: "tenant"},
+				},
+			},
+		},
+	}
+
+	mismatches := checkManagedDataSourceColumns(dataSource, client.CreateDataSourceOpts{
+		Columns: append([]*models.WebhookDataSourceColumnInput{
+			{Name: "id", Type: models.Int32PropelType, JsonProperty: "id"},
+			{Name: "price", Type: models.DoublePropelType, Nullable: true, JsonProperty: "price"},
+			{Name: "name", Type: models.StringPropelType, Nullable: true, JsonProperty: "name"},
+		}, defaultAirbyteColumns...),
+	})
+
+	assert.Equal(t, []string{
+		`column "price" of type INT32 can't hold property "price" of type DOUBLE`,
+		`property "name" has no column`,
+		`column "tenant" is not nullable but no property fills it`,
+	}, mismatches)
+}
+
+func TestCheckDataSourceCompatibility(t *testing.T) {
+	dataSource := &models.DataSource{
+		ConnectionSettings: models.ConnectionSettings{
+			WebhookConnectionSettings: models.WebhookConnectionSettings{
+				UniqueID: airbyteRawIdColumn,
+				Columns: []models.WebhookColumn{
+					{Name: "order_date_2", Type: models.DatePropelType, Nullable: true, JsonProperty: "order date"},
+					{Name: "customer_id", Type: models.StringPropelType, Nullable: true, JsonProperty: "customer_id"},
+					{Name: airbyteRawIdColumn, Type: models.StringPropelType, JsonProperty: airbyteRawIdColumn},
+				},
+			},
+		},
+	}
+
+	mismatches := checkDataSourceCompatibility(dataSource, client.CreateDataSourceOpts{
+		UniqueID: ptr(airbyteRawIdColumn),
+		Columns: []*models.WebhookDataSourceColumnInput{
+			{Name: "order_date_2", Type: models.DatePropelType, Nullable: true, JsonProperty: "order date"},
+			{Name: "customer", Type: models.StringPropelType, Nullable: true, JsonProperty: "customer_id"},
+			{Name: "note", Type: models.StringPropelType, Nullable: true, JsonProperty: "note"},
+			{Name: airbyteRawIdColumn, Type: models.StringPropelType, JsonProperty: airbyteRawIdColumn},
+			{Name: airbyteExtractedAtColumn, Type: models.TimestampPropelType, JsonProperty: airbyteExtractedAtColumn},
+		},
+	})
+
+	assert.Equal(t, []string{
+		`property "customer_id" is stored in column "customer_id", expected "customer"`,
+		`column "_airbyte_extracted_at" is missing`,
+	}, mismatches)
+}
@@ -0,0 +1,132 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+// recordExpressions filters the records of a stream and derives its computed columns, from the record data as sent
+// by the source.
+type recordExpressions struct {
+	filter   *compiledExpression
+	computed map[string]*compiledExpression
+	// filtered counts the records the filter left out, to report them once the sync ends.
+	filtered int
+}
+
+// compileRecordExpressions compiles the filter and computed columns of the stream, checking that they only read
+// properties of its schema. It returns nil when the stream has neither.
+func compileRecordExpressions(configuredStream airbyte.ConfiguredStream, streamConfig StreamConfig) (*recordExpressions, error) {
+	if streamConfig.Filter == "" && len(streamConfig.ComputedColumns) == 0 {
+		return nil, nil
+	}
+
+	compile := func(source string) (*compiledExpression, error) {
+		compiled, err := compileExpression(source)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, propertyName := range compiled.properties {
+			if _, ok := configuredStream.Stream.JSONSchema.Properties[propertyName]; !ok {
+				return nil, fmt.Errorf("unknown property %q", propertyName)
+			}
+		}
+
+		return compiled, nil
+	}
+
+	expressions := &recordExpressions{computed: map[string]*compiledExpression{}}
+
+	if streamConfig.Filter != "" {
+		filter, err := compile(streamConfig.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("filter: %w", err)
+		}
+
+		expressions.filter = filter
+	}
+
+	for columnName, computedColumn := range streamConfig.ComputedColumns {
+		if _, ok := configuredStream.Stream.JSONSchema.Properties[columnName]; ok {
+			return nil, fmt.Errorf("computed column %q collides with a stream property", columnName)
+		}
+
+		computed, err := compile(computedColumn.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("computed column %q: %w", columnName, err)
+		}
+
+		expressions.computed[columnName] = computed
+	}
+
+	return expressions, nil
+}
+
+// checkTransforms fails when a computed column reads a transformed property, as expressions read the record data as
+// sent by the source, so the column would store its values untransformed.
+func (re *recordExpressions) checkTransforms(transformedProperties []string) error {
+	if re == nil {
+		return nil
+	}
+
+	columnNames := make([]string, 0, len(re.computed))
+	for columnName := range re.computed {
+		columnNames = append(columnNames, columnName)
+	}
+	slices.Sort(columnNames)
+
+	for _, columnName := range columnNames {
+		for _, propertyName := range re.computed[columnName].properties {
+			if slices.Contains(transformedProperties, propertyName) {
+				return fmt.Errorf("computed column %q reads transformed property %q, whose values it would store untransformed", columnName, propertyName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// addComputedColumns adds the computed columns to the stream schema. Their column types are those declared in the
+// configuration, whatever their schema type.
+func addComputedColumns(configuredStream airbyte.ConfiguredStream, expressions *recordExpressions) (airbyte.ConfiguredStream, error) {
+	if expressions == nil || len(expressions.computed) == 0 {
+		return configuredStream, nil
+	}
+
+	properties := make(map[string]airbyte.PropertySpec, len(configuredStream.Stream.JSONSchema.Properties)+len(expressions.computed))
+	for propertyName, propertySpec := range configuredStream.Stream.JSONSchema.Properties {
+		properties[propertyName] = propertySpec
+	}
+
+	for columnName := range expressions.computed {
+		if _, ok := properties[columnName]; ok {
+			return configuredStream, fmt.Errorf("computed column %q collides with a column of the stream", columnName)
+		}
+
+		properties[columnName] = airbyte.PropertySpec{
+			PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}},
+		}
+	}
+
+	configuredStream.Stream.JSONSchema.Properties = properties
+
+	return configuredStream, nil
+}
+
+// apply reports whether the record passes the filter, and when it does, sets the computed columns of the record.
+// Expressions read the data as sent by the source, while the record may be wrapped for raw streams.
+func (re *recordExpressions) apply(data, recordMap map[string]any) bool {
+	if re.filter != nil && !re.filter.matches(data) {
+		re.filtered++
+		return false
+	}
+
+	for columnName, computed := range re.computed {
+		recordMap[columnName] = computed.evaluate(data)
+	}
+
+	return true
+}
@@ -0,0 +1,61 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestRecordExpressions(t *testing.T) {
+	c := require.New(t)
+
+	var configuredCatalog airbyte.ConfiguredCatalog
+	c.NoError(UnmarshalFromPath("./test_files/configured_catalog_arrays.json", &configuredCatalog))
+	configuredStream := configuredCatalog.Streams[0]
+
+	expressions, err := compileRecordExpressions(configuredStream, StreamConfig{})
+	c.NoError(err)
+	c.Nil(expressions)
+
+	expressions, err = compileRecordExpressions(configuredStream, StreamConfig{
+		Filter: "id > 1",
+		ComputedColumns: map[string]ComputedColumn{
+			"double_id": {Expression: "id * 2", Type: "INT64"},
+		},
+	})
+	c.NoError(err)
+
+	computed, err := addComputedColumns(configuredStream, expressions)
+	c.NoError(err)
+	c.Contains(computed.Stream.JSONSchema.Properties, "double_id")
+	c.NotContains(configuredStream.Stream.JSONSchema.Properties, "double_id", "the configured stream is left untouched")
+
+	record := map[string]any{"id": float64(1)}
+	c.False(expressions.apply(record, record))
+	c.Equal(map[string]any{"id": float64(1)}, record)
+
+	record = map[string]any{"id": float64(2)}
+	c.True(expressions.apply(record, record))
+	c.Equal(map[string]any{"id": float64(2), "double_id": float64(4)}, record)
+	c.Equal(1, expressions.filtered)
+
+	_, err = compileRecordExpressions(configuredStream, StreamConfig{Filter: `status != "test"`})
+	c.EqualError(err, `filter: unknown property "status"`)
+
+	_, err = compileRecordExpressions(configuredStream, StreamConfig{
+		ComputedColumns: map[string]ComputedColumn{"tags": {Expression: "lower(id)", Type: "STRING"}},
+	})
+	c.EqualError(err, `computed column "tags" collides with a stream property`)
+
+	expressions, err = compileRecordExpressions(configuredStream, StreamConfig{
+		Filter: "id > 1",
+		ComputedColumns: map[string]ComputedColumn{
+			"first_item": {Expression: "coalesce(line_items, tags)", Type: "STRING"},
+		},
+	})
+	c.NoError(err)
+	c.NoError(expressions.checkTransforms([]string{"id"}), "filters store nothing")
+	c.EqualError(expressions.checkTransforms([]string{"tags"}), `computed column "first_item" reads transformed property "tags", whose values it would store untransformed`)
+}
@@ -1,6 +1,385 @@
 package connector
 
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	// embeds the timezone database, as the connector image has none
+	_ "time/tzdata"
+
+	"github.com/propeldata/go-client/models"
+)
+
+var connectionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// DriftPolicy defines what happens when an existing Data Source no longer matches its configured stream.
+type DriftPolicy string
+
+const (
+	// DriftPolicyFail stops the sync with a configuration error.
+	DriftPolicyFail DriftPolicy = "fail"
+	// DriftPolicyRecreate deletes the Data Source and Data Pool and creates them again.
+	DriftPolicyRecreate DriftPolicy = "recreate"
+)
+
+// SchemaChangePolicy defines how changes to a stream schema are applied to its existing Data Source.
+type SchemaChangePolicy string
+
+const (
+	// SchemaChangePolicyAdd adds new properties as columns and fails on type changes the columns can't hold.
+	SchemaChangePolicyAdd SchemaChangePolicy = "add"
+	// SchemaChangePolicyIgnore logs schema changes and leaves the Data Source untouched.
+	SchemaChangePolicyIgnore SchemaChangePolicy = "ignore"
+	// SchemaChangePolicyFail stops the sync with a configuration error on any schema change.
+	SchemaChangePolicyFail SchemaChangePolicy = "fail"
+)
+
+// DestructiveOperationsMode defines whether the connector may delete data from Data Pools.
+type DestructiveOperationsMode string
+
+const (
+	// DestructiveOperationsAllow runs overwrite truncations, full resets and recreations.
+	DestructiveOperationsAllow DestructiveOperationsMode = "allow"
+	// DestructiveOperationsDryRun logs and audits destructive operations without running them.
+	DestructiveOperationsDryRun DestructiveOperationsMode = "dry_run"
+	// DestructiveOperationsDeny fails the sync whenever a destructive operation is needed.
+	DestructiveOperationsDeny DestructiveOperationsMode = "deny"
+)
+
+// TimeStorage defines how time-of-day values are stored.
+type TimeStorage string
+
+const (
+	// TimeStorageString stores time-of-day values as strings, as sent by the source.
+	TimeStorageString TimeStorage = "string"
+	// TimeStorageSeconds stores time-of-day values as seconds since midnight in UTC.
+	TimeStorageSeconds TimeStorage = "seconds"
+)
+
+// StreamConfig holds the settings of a single stream.
+type StreamConfig struct {
+	FlattenDepth         int                       `json:"flatten_depth,omitempty"`
+	FlattenSeparator     string                    `json:"flatten_separator,omitempty"`
+	KeepFlattenedObjects bool                      `json:"keep_flattened_objects,omitempty"`
+	NormalizeArrays      []string                  `json:"normalize_arrays,omitempty"`
+	Raw                  bool                      `json:"raw,omitempty"`
+	DataSource           string                    `json:"data_source,omitempty"`
+	Columns              ColumnMapping             `json:"columns,omitempty"`
+	Transforms           map[string]Transform      `json:"transforms,omitempty"`
+	Filter               string                    `json:"filter,omitempty"`
+	ComputedColumns      map[string]ComputedColumn `json:"computed_columns,omitempty"`
+	Routing              *RoutingConfig            `json:"routing,omitempty"`
+}
+
+// ComputedColumn is a column derived from the record data by an expression, of the declared Propel type.
+type ComputedColumn struct {
+	Expression string `json:"expression"`
+	Type       string `json:"type"`
+}
+
+// ColumnMapping shapes the columns of the Data Source of a stream. Rename maps property names to column names,
+// Exclude lists the properties left out of the Data Source and its records, Order lists the properties whose columns
+// come first, and Constants maps the names of additional columns to the value every record gets.
+type ColumnMapping struct {
+	Rename    map[string]string `json:"rename,omitempty"`
+	Exclude   []string          `json:"exclude,omitempty"`
+	Order     []string          `json:"order,omitempty"`
+	Constants map[string]any    `json:"constants,omitempty"`
+}
+
 type Config struct {
-	ApplicationID     string `json:"application_id"`
-	ApplicationSecret string `json:"application_secret"`
+	ApplicationID                     string                       `json:"application_id"`
+	ApplicationSecret                 string                       `json:"application_secret"`
+	DriftPolicy                       DriftPolicy                  `json:"data_source_drift_policy,omitempty"`
+	SchemaChangePolicy                SchemaChangePolicy           `json:"schema_change_policy,omitempty"`
+	ConnectionID                      string                       `json:"connection_id,omitempty"`
+	AllowUnownedDestructiveOperations bool                         `json:"allow_unowned_destructive_operations,omitempty"`
+	DestructiveOperations             DestructiveOperationsMode    `json:"destructive_operations,omitempty"`
+	DestructiveOperationsAllowList    []string                     `json:"destructive_operations_allow_list,omitempty"`
+	AuditLogPath                      string                       `json:"audit_log_path,omitempty"`
+	SourceTimezone                    string                       `json:"source_timezone,omitempty"`
+	TimeStorage                       TimeStorage                  `json:"time_storage,omitempty"`
+	NarrowTypes                       bool                         `json:"narrow_types,omitempty"`
+	IgnoreRequired                    bool                         `json:"ignore_required,omitempty"`
+	Streams                           map[string]StreamConfig      `json:"streams,omitempty"`
+	ColumnTypeOverrides               map[string]map[string]string `json:"column_type_overrides,omitempty"`
+	DataSourceNameTemplate            string                       `json:"data_source_name_template,omitempty"`
+	DataSourceNamePrefix              string                       `json:"data_source_name_prefix,omitempty"`
+	PIISalt                           string                       `json:"pii_salt,omitempty"`
+	MetadataColumns                   []MetadataColumn             `json:"metadata_columns,omitempty"`
+	ConnectionLabel                   string                       `json:"connection_label,omitempty"`
+	Unions                            map[string]UnionConfig       `json:"unions,omitempty"`
+
+	sourceLocation *time.Location
+}
+
+// Validate checks the configuration values and sets the defaults of the optional ones.
+func (c *Config) Validate() error {
+	switch c.DriftPolicy {
+	case "":
+		c.DriftPolicy = DriftPolicyFail
+	case DriftPolicyFail, DriftPolicyRecreate:
+	default:
+		return fmt.Errorf("invalid data_source_drift_policy %q, expected %q or %q", c.DriftPolicy, DriftPolicyFail, DriftPolicyRecreate)
+	}
+
+	switch c.SchemaChangePolicy {
+	case "":
+		c.SchemaChangePolicy = SchemaChangePolicyAdd
+	case SchemaChangePolicyAdd, SchemaChangePolicyIgnore, SchemaChangePolicyFail:
+	default:
+		return fmt.Errorf("invalid schema_change_policy %q, expected %q, %q or %q", c.SchemaChangePolicy, SchemaChangePolicyAdd, SchemaChangePolicyIgnore, SchemaChangePolicyFail)
+	}
+
+	switch c.DestructiveOperations {
+	case "":
+		c.DestructiveOperations = DestructiveOperationsAllow
+	case DestructiveOperationsAllow, DestructiveOperationsDryRun, DestructiveOperationsDeny:
+	default:
+		return fmt.Errorf("invalid destructive_operations %q, expected %q, %q or %q", c.DestructiveOperations, DestructiveOperationsAllow, DestructiveOperationsDryRun, DestructiveOperationsDeny)
+	}
+
+	switch c.TimeStorage {
+	case "":
+		c.TimeStorage = TimeStorageString
+	case TimeStorageString, TimeStorageSeconds:
+	default:
+		return fmt.Errorf("invalid time_storage %q, expected %q or %q", c.TimeStorage, TimeStorageString, TimeStorageSeconds)
+	}
+
+	for streamName, streamConfig := range c.Streams {
+		if streamConfig.FlattenDepth < 0 {
+			return fmt.Errorf("invalid flatten_depth %d of stream %q, expected 0 or more", streamConfig.FlattenDepth, streamName)
+		}
+
+		if streamConfig.Raw && (streamConfig.FlattenDepth > 0 || len(streamConfig.NormalizeArrays) > 0) {
+			return fmt.Errorf("stream %q in raw mode can't flatten objects or normalize arrays", streamName)
+		}
+
+		if streamConfig.Raw && len(streamConfig.Transforms) > 0 {
+			return fmt.Errorf("stream %q in raw mode can't transform columns, as its records are stored whole", streamName)
+		}
+
+		if streamConfig.Routing != nil {
+			if err := streamConfig.Routing.validate(); err != nil {
+				return fmt.Errorf("invalid routing of stream %q: %w", streamName, err)
+			}
+
+			if len(streamConfig.NormalizeArrays) > 0 || streamConfig.DataSource != "" {
+				return fmt.Errorf("routed stream %q can't normalize arrays or be written to a data_source", streamName)
+			}
+
+			if _, ok := streamConfig.Transforms[streamConfig.Routing.Field]; ok {
+				return fmt.Errorf("routing field %q of stream %q can't be transformed, as its values name the Data Sources", streamConfig.Routing.Field, streamName)
+			}
+		}
+
+		if err := streamConfig.Columns.validate(); err != nil {
+			return fmt.Errorf("invalid columns of stream %q: %w", streamName, err)
+		}
+
+		if streamConfig.Filter != "" {
+			if _, err := compileExpression(streamConfig.Filter); err != nil {
+				return fmt.Errorf("invalid filter of stream %q: %w", streamName, err)
+			}
+		}
+
+		for columnName, computedColumn := range streamConfig.ComputedColumns {
+			if _, err := compileExpression(computedColumn.Expression); err != nil {
+				return fmt.Errorf("invalid expression of computed column %q of stream %q: %w", columnName, streamName, err)
+			}
+
+			if _, ok := parsePropelType(computedColumn.Type); !ok {
+				return fmt.Errorf("invalid type %q of computed column %q of stream %q, expected one of %s", computedColumn.Type, columnName, streamName, propelTypeNames())
+			}
+		}
+
+		for columnName, transform := range streamConfig.Transforms {
+			if !slices.Contains(transforms, transform) {
+				return fmt.Errorf("invalid transform %q of column %q of stream %q, expected %q, %q, %q or %q", transform, columnName, streamName, TransformHash, TransformMask, TransformTokenize, TransformDrop)
+			}
+
+			if c.PIISalt == "" && (transform == TransformHash || transform == TransformTokenize) {
+				return fmt.Errorf("transform %q of column %q of stream %q requires a pii_salt", transform, columnName, streamName)
+			}
+
+			if _, ok := streamConfig.Columns.Constants[columnName]; ok {
+				return fmt.Errorf("constant column %q of stream %q can't be transformed", columnName, streamName)
+			}
+		}
+	}
+
+	unionOf := map[string]string{}
+	for unionName, union := range c.Unions {
+		if len(union.Streams) < 2 {
+			return fmt.Errorf("union %q needs at least 2 streams", unionName)
+		}
+
+		for _, streamName := range union.Streams {
+			if other, ok := unionOf[streamName]; ok {
+				return fmt.Errorf("stream %q belongs to both unions %q and %q", streamName, other, unionName)
+			}
+
+			unionOf[streamName] = unionName
+
+			if _, ok := c.Streams[streamName]; ok {
+				return fmt.Errorf("stream %q of union %q has settings of its own, which are ignored, key them by the union name instead", streamName, unionName)
+			}
+
+			if _, ok := c.ColumnTypeOverrides[streamName]; ok {
+				return fmt.Errorf("stream %q of union %q has column_type_overrides of its own, which are ignored, key them by the union name instead", streamName, unionName)
+			}
+		}
+
+		if c.Streams[unionName].Raw {
+			return fmt.Errorf("union %q can't be written in raw mode", unionName)
+		}
+
+		if isAirbyteColumn(union.discriminatorColumn()) {
+			return fmt.Errorf("discriminator column %q of union %q is reserved", union.discriminatorColumn(), unionName)
+		}
+	}
+
+	for _, metadata := range c.MetadataColumns {
+		switch metadata {
+		case MetadataLoadedAt, MetadataSyncID, MetadataStream, MetadataNamespace:
+		case MetadataConnectionLabel:
+			if c.ConnectionLabel == "" {
+				return fmt.Errorf("metadata column %q requires a connection_label", metadata)
+			}
+		default:
+			return fmt.Errorf("invalid metadata column %q, expected %q, %q, %q, %q or %q", metadata, MetadataLoadedAt, MetadataSyncID, MetadataStream, MetadataNamespace, MetadataConnectionLabel)
+		}
+	}
+
+	for streamName, overrides := range c.ColumnTypeOverrides {
+		// property paths and column names of the same column would make the override depend on map iteration
+		separator := c.streamConfig(streamName).FlattenSeparator
+		columnPaths := map[string]string{}
+		for columnPath, typeName := range overrides {
+			if _, ok := parsePropelType(typeName); !ok {
+				return fmt.Errorf("invalid column_type_overrides type %q of column %q of stream %q, expected one of %s", typeName, columnPath, streamName, propelTypeNames())
+			}
+
+			columnName := strings.ReplaceAll(columnPath, ".", separator)
+			if other, ok := columnPaths[columnName]; ok {
+				return fmt.Errorf("column_type_overrides %q and %q of stream %q both override column %q", min(other, columnPath), max(other, columnPath), streamName, columnName)
+			}
+
+			columnPaths[columnName] = columnPath
+		}
+	}
+
+	if c.DataSourceNameTemplate != "" {
+		if err := validateDataSourceNameTemplate(c.DataSourceNameTemplate); err != nil {
+			return err
+		}
+	}
+
+	if c.SourceTimezone == "" {
+		c.SourceTimezone = "UTC"
+	}
+
+	location, err := time.LoadLocation(c.SourceTimezone)
+	if err != nil {
+		return fmt.Errorf("invalid source_timezone %q: %w", c.SourceTimezone, err)
+	}
+
+	c.sourceLocation = location
+
+	if c.ConnectionID == "" {
+		return fmt.Errorf("connection_id is required, it tells the Data Sources the connection created from those of others")
+	}
+
+	if !connectionIDPattern.MatchString(c.ConnectionID) {
+		return fmt.Errorf("invalid connection_id %q, only up to 64 letters, digits, underscores and hyphens are allowed", c.ConnectionID)
+	}
+
+	return nil
+}
+
+// validate checks that renamed columns get distinct names that need no sanitizing, and that excluded properties are not
+// renamed.
+func (cm ColumnMapping) validate() error {
+	propertyNames := map[string]string{}
+	for propertyName, columnName := range cm.Rename {
+		if columnName == "" {
+			return fmt.Errorf("property %q is renamed to an empty column name", propertyName)
+		}
+
+		if sanitized := sanitizeColumnName(columnName); sanitized != columnName {
+			return fmt.Errorf("property %q is renamed to invalid column name %q, expected up to 64 letters, digits and underscores, starting with no digit and other than a SQL keyword, such as %q", propertyName, columnName, sanitized)
+		}
+
+		if slices.Contains(cm.Exclude, propertyName) {
+			return fmt.Errorf("property %q is both renamed and excluded", propertyName)
+		}
+
+		if other, ok := propertyNames[strings.ToLower(columnName)]; ok {
+			return fmt.Errorf("properties %q and %q are both renamed to column %q", min(other, propertyName), max(other, propertyName), columnName)
+		}
+
+		propertyNames[strings.ToLower(columnName)] = propertyName
+	}
+
+	return nil
+}
+
+// location returns the timezone of timestamps without an offset.
+func (c Config) location() *time.Location {
+	if c.sourceLocation == nil {
+		return time.UTC
+	}
+
+	return c.sourceLocation
+}
+
+// streamConfig returns the settings of the stream, with their defaults set.
+func (c Config) streamConfig(streamName string) StreamConfig {
+	streamConfig := c.Streams[streamName]
+	if streamConfig.FlattenSeparator == "" {
+		streamConfig.FlattenSeparator = "_"
+	}
+
+	return streamConfig
+}
+
+// columnTypeOverride returns the Propel type configured for the column of the stream.
+// Overrides are keyed by column name, or by property path with nested properties of flattened objects separated by dots.
+// Validate makes sure that at most one of them names the column.
+func (c Config) columnTypeOverride(streamName, columnName string) (models.PropelType, bool) {
+	overrides := c.ColumnTypeOverrides[streamName]
+	if typeName, ok := overrides[columnName]; ok {
+		return parsePropelType(typeName)
+	}
+
+	separator := c.streamConfig(streamName).FlattenSeparator
+	for columnPath, typeName := range overrides {
+		if strings.ReplaceAll(columnPath, ".", separator) == columnName {
+			return parsePropelType(typeName)
+		}
+	}
+
+	return models.PropelType{}, false
+}
+
+func parsePropelType(typeName string) (models.PropelType, bool) {
+	for _, propelType := range models.Types {
+		if strings.EqualFold(propelType.String(), typeName) {
+			return propelType, true
+		}
+	}
+
+	return models.PropelType{}, false
+}
+
+func propelTypeNames() string {
+	names := make([]string, 0, len(models.Types))
+	for _, propelType := range models.Types {
+		names = append(names, propelType.String())
+	}
+
+	return strings.Join(names, ", ")
 }
@@ -0,0 +1,42 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_ColumnTypeOverride(t *testing.T) {
+	dstCfg := Config{
+		ColumnTypeOverrides: map[string]map[string]string{
+			"orders": {"customer_id": "string", "address.updated_at": "TIMESTAMP"},
+		},
+		Streams: map[string]StreamConfig{
+			"orders": {FlattenDepth: 1, FlattenSeparator: "__"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		dataSourceName string
+		columnName     string
+		expectedType   models.PropelType
+		expectedFound  bool
+	}{
+		{name: "Column name", dataSourceName: "orders", columnName: "customer_id", expectedType: models.StringPropelType, expectedFound: true},
+		{name: "Property path", dataSourceName: "orders", columnName: "address__updated_at", expectedType: models.TimestampPropelType, expectedFound: true},
+		{name: "Column without override", dataSourceName: "orders", columnName: "id"},
+		{name: "Stream without overrides", dataSourceName: "tacos", columnName: "customer_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			a := assert.New(st)
+
+			propelType, found := dstCfg.columnTypeOverride(tt.dataSourceName, tt.columnName)
+			a.Equal(tt.expectedFound, found)
+			a.Equal(tt.expectedType, propelType)
+		})
+	}
+}
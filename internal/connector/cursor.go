@@ -0,0 +1,315 @@
+package connector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/propeldata/go-client/models"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+// missingCursorVersion is the version of records whose numeric cursor is missing or can't be converted. It is lower
+// than any real version, so that these records never replace those with a cursor.
+const missingCursorVersion int64 = math.MinInt64
+
+// cursorKind is how the values of a cursor are converted into versions that keep their order.
+type cursorKind int
+
+const (
+	// cursorKindUnordered cursors, such as strings without a date or date-time format, have no version that keeps
+	// their order.
+	cursorKindUnordered cursorKind = iota
+	// cursorKindInteger cursors are used as is.
+	cursorKindInteger
+	// cursorKindNumber cursors are encoded by the bits of their float value, which order as the values do.
+	cursorKindNumber
+	// cursorKindTime cursors become microseconds since the Unix epoch, as does the extraction time of records
+	// without one.
+	cursorKindTime
+)
+
+// cursorVersion describes how the ReplacingMergeTree version of a record is derived from its cursor.
+type cursorVersion struct {
+	// path is the cursor field path within the record, e.g. ["meta", "updated_at"].
+	path []string
+	// column is the Data Source column used as the ReplacingMergeTree ver.
+	column string
+	// columnType is the Propel type of the version column.
+	columnType models.PropelType
+	// kind is how the cursor values are converted into the dedicated version column.
+	kind cursorKind
+}
+
+// newCursorVersion returns how the version of a de-duplicating Data Source is derived from the stream cursor.
+// Top-level cursors that every record holds, with a type ReplacingMergeTree accepts as ver, are used as is, while
+// nested and nullable cursors, and cursors of any other type, are converted into a dedicated numeric version column.
+// A non-nil cursorType is the type the top-level cursor column is overridden with.
+func newCursorVersion(configuredStream airbyte.ConfiguredStream, cursorType *models.PropelType) (*cursorVersion, error) {
+	if len(configuredStream.CursorField) == 0 {
+		return &cursorVersion{
+			column:     airbyteExtractedAtColumn,
+			columnType: models.TimestampPropelType,
+		}, nil
+	}
+
+	if len(configuredStream.CursorField) == 1 {
+		propertySpec, ok := configuredStream.Stream.JSONSchema.Properties[configuredStream.CursorField[0]]
+		required := slices.Contains(configuredStream.Stream.JSONSchema.Required, configuredStream.CursorField[0])
+		if ok && required && !acceptsNull(propertySpec.PropertyType) {
+			columnType, err := ConvertAirbyteTypeToPropelType(propertySpec.PropertyType)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert cursor %q type: %w", configuredStream.CursorField[0], err)
+			}
+
+			if cursorType != nil {
+				columnType = *cursorType
+			}
+
+			if isVersionType(columnType) {
+				return &cursorVersion{
+					path:       configuredStream.CursorField,
+					column:     configuredStream.CursorField[0],
+					columnType: columnType,
+				}, nil
+			}
+		}
+	}
+
+	return &cursorVersion{
+		path:       configuredStream.CursorField,
+		column:     airbyteCursorVersionColumn,
+		columnType: models.Int64PropelType,
+		kind:       cursorKindOf(configuredStream),
+	}, nil
+}
+
+// cursorKindOf returns how the cursor values of the stream are converted into versions, by the type of the cursor
+// property. Cursors missing from the stream schema are unordered.
+func cursorKindOf(configuredStream airbyte.ConfiguredStream) cursorKind {
+	properties := configuredStream.Stream.JSONSchema.Properties
+
+	var propertySpec airbyte.PropertySpec
+	for _, key := range configuredStream.CursorField {
+		spec, ok := properties[key]
+		if !ok {
+			return cursorKindUnordered
+		}
+
+		propertySpec, properties = spec, spec.Properties
+	}
+
+	columnType, err := ConvertAirbyteTypeToPropelType(propertySpec.PropertyType)
+	if err != nil {
+		return cursorKindUnordered
+	}
+
+	switch columnType {
+	case models.Int8PropelType, models.Int16PropelType, models.Int32PropelType, models.Int64PropelType:
+		return cursorKindInteger
+	case models.FloatPropelType, models.DoublePropelType:
+		return cursorKindNumber
+	case models.DatePropelType, models.TimestampPropelType:
+		return cursorKindTime
+	}
+
+	return cursorKindUnordered
+}
+
+// cursorVersionFromDataSource rebuilds the cursor version of an existing Data Source from its ReplacingMergeTree ver.
+// It returns nil when the Data Source does not de-duplicate records.
+func cursorVersionFromDataSource(configuredStream airbyte.ConfiguredStream, dataSource *models.DataSource) *cursorVersion {
+	tableSettings := dataSource.ConnectionSettings.WebhookConnectionSettings.TableSettings
+	if tableSettings == nil || tableSettings.Engine == nil || tableSettings.Engine.ReplacingMergeTreeTableEngine.Ver == "" {
+		return nil
+	}
+
+	ver := tableSettings.Engine.ReplacingMergeTreeTableEngine.Ver
+	if ver == airbyteExtractedAtColumn || len(configuredStream.CursorField) == 0 {
+		return nil
+	}
+
+	version := &cursorVersion{
+		path:       configuredStream.CursorField,
+		column:     ver,
+		columnType: models.Int64PropelType,
+		kind:       cursorKindOf(configuredStream),
+	}
+
+	for _, column := range dataSource.ConnectionSettings.WebhookConnectionSettings.Columns {
+		if column.Name == ver {
+			version.columnType = column.Type
+		}
+	}
+
+	return version
+}
+
+// existingCursorVersion returns the cursor version of an existing Data Source whose ReplacingMergeTree ver still
+// versions the stream, either as the dedicated version column or as the column of its top-level cursor. Data Sources
+// created before nullable cursors got a dedicated version column keep their cursor column as ver. It returns nil for
+// a nil Data Source or any other ver.
+func existingCursorVersion(configuredStream airbyte.ConfiguredStream, dataSource *models.DataSource) *cursorVersion {
+	if dataSource == nil {
+		return nil
+	}
+
+	version := cursorVersionFromDataSource(configuredStream, dataSource)
+	if version == nil || !isVersionType(version.columnType) {
+		return nil
+	}
+
+	if version.isDedicated() {
+		return version
+	}
+
+	if len(configuredStream.CursorField) != 1 {
+		return nil
+	}
+
+	// the version refers to the cursor property, which the Data Source stores in the ver column
+	column, ok := columnsByProperty(dataSource)[configuredStream.CursorField[0]]
+	if !ok || column.Name != version.column {
+		return nil
+	}
+
+	version.column = configuredStream.CursorField[0]
+
+	return version
+}
+
+// isDedicated reports whether the version is stored in its own column rather than in the cursor column.
+func (cv *cursorVersion) isDedicated() bool {
+	return cv.column == airbyteCursorVersionColumn
+}
+
+// apply sets the dedicated version column of the record. Cursors used as is are left untouched, as they are values
+// of the source. Records without a date or timestamp cursor are versioned by their extraction time, while those
+// without a numeric cursor get missingCursorVersion, as extraction times don't compare with their values.
+func (cv *cursorVersion) apply(recordMap map[string]any) {
+	if !cv.isDedicated() {
+		return
+	}
+
+	value, found := lookupPath(recordMap, cv.path)
+	if version, ok := cv.version(value, found); ok {
+		recordMap[cv.column] = version
+		return
+	}
+
+	recordMap[cv.column] = missingCursorVersion
+	if cv.kind != cursorKindTime {
+		return
+	}
+
+	if extractedAt, ok := recordMap[airbyteExtractedAtColumn].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, extractedAt); err == nil {
+			recordMap[cv.column] = t.UnixMicro()
+		}
+	}
+}
+
+// version converts a cursor value into a version that keeps the order of the values. Integers are used as is, other
+// numbers are encoded by orderedFloatVersion, and dates and timestamps become microseconds since the Unix epoch. It
+// returns false for missing cursors and those that don't convert.
+func (cv *cursorVersion) version(value any, found bool) (int64, bool) {
+	if !found || value == nil {
+		return 0, false
+	}
+
+	switch cv.kind {
+	case cursorKindInteger:
+		switch v := value.(type) {
+		case int64:
+			return v, true
+		case int:
+			return int64(v), true
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int64(v), true
+			}
+		case json.Number:
+			if intValue, err := v.Int64(); err == nil {
+				return intValue, true
+			}
+		case string:
+			if intValue, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return intValue, true
+			}
+		}
+	case cursorKindNumber:
+		var floatValue float64
+		var err error
+
+		switch v := value.(type) {
+		case float64:
+			floatValue = v
+		case int64:
+			floatValue = float64(v)
+		case int:
+			floatValue = float64(v)
+		case json.Number:
+			floatValue, err = v.Float64()
+		case string:
+			floatValue, err = strconv.ParseFloat(strings.TrimSpace(v), 64)
+		default:
+			return 0, false
+		}
+
+		if err == nil && !math.IsNaN(floatValue) {
+			return orderedFloatVersion(floatValue), true
+		}
+	case cursorKindTime:
+		if v, ok := value.(string); ok {
+			for _, layout := range timestampLayouts {
+				if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+					return t.UnixMicro(), true
+				}
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// orderedFloatVersion encodes a float into an integer of the same order, so that fractional cursors such as 1.2 and
+// 1.9 don't tie. The sign bit of positive floats is flipped and every bit of negative ones, which orders their bits
+// as unsigned integers, then shifted into the range of signed ones.
+func orderedFloatVersion(value float64) int64 {
+	bits := math.Float64bits(value)
+	if bits>>63 == 1 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+
+	return int64(bits ^ 1<<63)
+}
+
+// lookupPath returns the value found at the given path of nested objects.
+func lookupPath(data map[string]any, path []string) (any, bool) {
+	var current any = data
+
+	for _, key := range path {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func isVersionType(propelType models.PropelType) bool {
+	return slices.Contains([]models.PropelType{models.Int64PropelType, models.DatePropelType, models.TimestampPropelType}, propelType)
+}
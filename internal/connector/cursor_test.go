@@ -0,0 +1,287 @@
+package connector
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestNewCursorVersion(t *testing.T) {
+	stringType := airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}}
+	dateTimeType := airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}, Format: airbyte.DateTime}
+	objectType := airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Object}}}
+
+	nullableDateTimeType := airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Null, airbyte.String}}, Format: airbyte.DateTime}
+
+	properties := map[string]airbyte.PropertySpec{
+		"updated_at":  {PropertyType: dateTimeType},
+		"modified_at": {PropertyType: nullableDateTimeType},
+		"deleted_at":  {PropertyType: dateTimeType},
+		"version":     {PropertyType: stringType},
+		"meta": {
+			PropertyType: objectType,
+			Properties: map[string]airbyte.PropertySpec{
+				"updated_at": {PropertyType: dateTimeType},
+			},
+		},
+	}
+
+	tests := []struct {
+		name               string
+		cursorField        []string
+		cursorType         *models.PropelType
+		expectedColumn     string
+		expectedColumnType models.PropelType
+	}{
+		{
+			name:               "No cursor",
+			expectedColumn:     airbyteExtractedAtColumn,
+			expectedColumnType: models.TimestampPropelType,
+		},
+		{
+			name:               "Top-level timestamp cursor",
+			cursorField:        []string{"updated_at"},
+			expectedColumn:     "updated_at",
+			expectedColumnType: models.TimestampPropelType,
+		},
+		{
+			name:               "Nullable top-level timestamp cursor",
+			cursorField:        []string{"modified_at"},
+			expectedColumn:     airbyteCursorVersionColumn,
+			expectedColumnType: models.Int64PropelType,
+		},
+		{
+			name:               "Optional top-level timestamp cursor",
+			cursorField:        []string{"deleted_at"},
+			expectedColumn:     airbyteCursorVersionColumn,
+			expectedColumnType: models.Int64PropelType,
+		},
+		{
+			name:               "Top-level string cursor",
+			cursorField:        []string{"version"},
+			expectedColumn:     airbyteCursorVersionColumn,
+			expectedColumnType: models.Int64PropelType,
+		},
+		{
+			name:               "Top-level string cursor overridden with a timestamp",
+			cursorField:        []string{"version"},
+			cursorType:         ptr(models.TimestampPropelType),
+			expectedColumn:     "version",
+			expectedColumnType: models.TimestampPropelType,
+		},
+		{
+			name:               "Top-level timestamp cursor overridden with a string",
+			cursorField:        []string{"updated_at"},
+			cursorType:         ptr(models.StringPropelType),
+			expectedColumn:     airbyteCursorVersionColumn,
+			expectedColumnType: models.Int64PropelType,
+		},
+		{
+			name:               "Nested cursor",
+			cursorField:        []string{"meta", "updated_at"},
+			expectedColumn:     airbyteCursorVersionColumn,
+			expectedColumnType: models.Int64PropelType,
+		},
+		{
+			name:               "Cursor missing from schema",
+			cursorField:        []string{"missing"},
+			expectedColumn:     airbyteCursorVersionColumn,
+			expectedColumnType: models.Int64PropelType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			a := assert.New(st)
+
+			version, err := newCursorVersion(airbyte.ConfiguredStream{
+				Stream:      airbyte.Stream{JSONSchema: airbyte.Properties{Properties: properties, Required: []string{"updated_at", "modified_at", "version"}}},
+				CursorField: tt.cursorField,
+			}, tt.cursorType)
+			a.NoError(err)
+			a.Equal(tt.expectedColumn, version.column)
+			a.Equal(tt.expectedColumnType, version.columnType)
+		})
+	}
+}
+
+func TestExistingCursorVersion(t *testing.T) {
+	dataSource := func(ver string) *models.DataSource {
+		return &models.DataSource{ConnectionSettings: models.ConnectionSettings{WebhookConnectionSettings: models.WebhookConnectionSettings{
+			Columns: []models.WebhookColumn{
+				{Name: "updated_at", Type: models.TimestampPropelType, Nullable: true, JsonProperty: "updated_at"},
+				{Name: "name", Type: models.StringPropelType, Nullable: true, JsonProperty: "name"},
+				{Name: airbyteCursorVersionColumn, Type: models.Int64PropelType, JsonProperty: airbyteCursorVersionColumn},
+			},
+			TableSettings: &models.TableSettings{Engine: &models.Engine{ReplacingMergeTreeTableEngine: models.ReplacingMergeTreeTableEngine{Ver: ver}}},
+		}}}
+	}
+
+	tests := []struct {
+		name            string
+		cursorField     []string
+		dataSource      *models.DataSource
+		expectedVersion *cursorVersion
+	}{
+		{name: "No Data Source", cursorField: []string{"updated_at"}},
+		{
+			name:            "Cursor column",
+			cursorField:     []string{"updated_at"},
+			dataSource:      dataSource("updated_at"),
+			expectedVersion: &cursorVersion{path: []string{"updated_at"}, column: "updated_at", columnType: models.TimestampPropelType},
+		},
+		{
+			name:            "Dedicated version column",
+			cursorField:     []string{"updated_at"},
+			dataSource:      dataSource(airbyteCursorVersionColumn),
+			expectedVersion: &cursorVersion{path: []string{"updated_at"}, column: airbyteCursorVersionColumn, columnType: models.Int64PropelType},
+		},
+		{name: "Column of another property", cursorField: []string{"updated_at"}, dataSource: dataSource("name")},
+		{name: "Extraction time", cursorField: []string{"updated_at"}, dataSource: dataSource(airbyteExtractedAtColumn)},
+		{name: "Nested cursor", cursorField: []string{"meta", "updated_at"}, dataSource: dataSource("updated_at")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			version := existingCursorVersion(airbyte.ConfiguredStream{CursorField: tt.cursorField}, tt.dataSource)
+			assert.Equal(st, tt.expectedVersion, version)
+		})
+	}
+}
+
+func TestCursorKindOf(t *testing.T) {
+	properties := map[string]airbyte.PropertySpec{
+		"seq":        {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Integer}}}},
+		"score":      {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Null, airbyte.Number}}}},
+		"ulid":       {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}}},
+		"updated_on": {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}, Format: airbyte.Date}},
+		"meta": {
+			PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Object}}},
+			Properties: map[string]airbyte.PropertySpec{
+				"updated_at": {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}, Format: airbyte.DateTime}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		cursorField  []string
+		expectedKind cursorKind
+	}{
+		{name: "Integer", cursorField: []string{"seq"}, expectedKind: cursorKindInteger},
+		{name: "Nullable number", cursorField: []string{"score"}, expectedKind: cursorKindNumber},
+		{name: "String", cursorField: []string{"ulid"}, expectedKind: cursorKindUnordered},
+		{name: "Date", cursorField: []string{"updated_on"}, expectedKind: cursorKindTime},
+		{name: "Nested timestamp", cursorField: []string{"meta", "updated_at"}, expectedKind: cursorKindTime},
+		{name: "Missing from schema", cursorField: []string{"meta", "deleted_at"}, expectedKind: cursorKindUnordered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			configuredStream := airbyte.ConfiguredStream{Stream: airbyte.Stream{JSONSchema: airbyte.Properties{Properties: properties}}, CursorField: tt.cursorField}
+			assert.Equal(st, tt.expectedKind, cursorKindOf(configuredStream))
+		})
+	}
+}
+
+func TestCursorVersion_Apply(t *testing.T) {
+	dedicated := func(kind cursorKind, path ...string) cursorVersion {
+		return cursorVersion{path: path, column: airbyteCursorVersionColumn, columnType: models.Int64PropelType, kind: kind}
+	}
+
+	tests := []struct {
+		name          string
+		version       cursorVersion
+		record        map[string]any
+		expectedValue any
+	}{
+		{
+			name:          "Nested timestamp cursor",
+			version:       dedicated(cursorKindTime, "meta", "updated_at"),
+			record:        map[string]any{"meta": map[string]any{"updated_at": "2024-01-16T04:36:36Z"}},
+			expectedValue: int64(1705379796000000),
+		},
+		{
+			name:          "Date cursor",
+			version:       dedicated(cursorKindTime, "day"),
+			record:        map[string]any{"day": "2024-01-16"},
+			expectedValue: int64(1705363200000000),
+		},
+		{
+			name:          "Missing timestamp cursor",
+			version:       dedicated(cursorKindTime, "meta", "updated_at"),
+			record:        map[string]any{"meta": nil, airbyteExtractedAtColumn: "2024-01-16T04:36:36.5Z"},
+			expectedValue: int64(1705379796500000),
+		},
+		{
+			name:          "Unparseable timestamp cursor",
+			version:       dedicated(cursorKindTime, "updated_at"),
+			record:        map[string]any{"updated_at": "yesterday", airbyteExtractedAtColumn: "2024-01-16T04:36:36Z"},
+			expectedValue: int64(1705379796000000),
+		},
+		{
+			name:          "Integer cursor",
+			version:       dedicated(cursorKindInteger, "seq"),
+			record:        map[string]any{"seq": json.Number("9007199254740993")},
+			expectedValue: int64(9007199254740993),
+		},
+		{
+			name:          "Integer string cursor",
+			version:       dedicated(cursorKindInteger, "seq"),
+			record:        map[string]any{"seq": "42"},
+			expectedValue: int64(42),
+		},
+		{
+			name:          "Fractional integer cursor",
+			version:       dedicated(cursorKindInteger, "seq"),
+			record:        map[string]any{"seq": json.Number("4.2")},
+			expectedValue: missingCursorVersion,
+		},
+		{
+			name:          "Number cursor",
+			version:       dedicated(cursorKindNumber, "score"),
+			record:        map[string]any{"score": json.Number("1.5")},
+			expectedValue: orderedFloatVersion(1.5),
+		},
+		{
+			name:          "Missing number cursor",
+			version:       dedicated(cursorKindNumber, "score"),
+			record:        map[string]any{airbyteExtractedAtColumn: "2024-01-16T04:36:36Z"},
+			expectedValue: missingCursorVersion,
+		},
+		{
+			name:          "Missing top-level timestamp cursor",
+			version:       cursorVersion{path: []string{"updated_at"}, column: "updated_at", columnType: models.TimestampPropelType},
+			record:        map[string]any{},
+			expectedValue: nil,
+		},
+		{
+			name:          "Present top-level timestamp cursor",
+			version:       cursorVersion{path: []string{"updated_at"}, column: "updated_at", columnType: models.TimestampPropelType},
+			record:        map[string]any{"updated_at": "2023-01-01T00:00:00Z"},
+			expectedValue: "2023-01-01T00:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			a := assert.New(st)
+
+			tt.version.apply(tt.record)
+			a.Equal(tt.expectedValue, tt.record[tt.version.column])
+		})
+	}
+}
+
+func TestOrderedFloatVersion(t *testing.T) {
+	values := []float64{math.Inf(-1), -1e300, -1.9, -1.2, -0.5, 0, 0.5, 1.2, 1.9, 2, 1e300, math.Inf(1)}
+
+	for i := 1; i < len(values); i++ {
+		assert.True(t, orderedFloatVersion(values[i-1]) < orderedFloatVersion(values[i]), "%v < %v", values[i-1], values[i])
+	}
+}
@@ -2,12 +2,14 @@ package connector
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"slices"
 	"strconv"
 	"strings"
@@ -21,8 +23,10 @@ import (
 )
 
 const (
-	airbyteExtractedAtColumn = "_airbyte_extracted_at"
-	airbyteRawIdColumn       = "_airbyte_raw_id"
+	airbyteExtractedAtColumn   = "_airbyte_extracted_at"
+	airbyteRawIdColumn         = "_airbyte_raw_id"
+	airbyteCursorVersionColumn = "_airbyte_cursor_version"
+	airbyteMetaColumn          = "_airbyte_meta"
 )
 
 var (
@@ -41,6 +45,13 @@ var (
 			Nullable:     false,
 			JsonProperty: airbyteExtractedAtColumn,
 		},
+		{
+			// Nullable, so it can be added to Data Sources created before change tracking existed
+			Name:         airbyteMetaColumn,
+			Type:         models.JsonPropelType,
+			Nullable:     true,
+			JsonProperty: airbyteMetaColumn,
+		},
 	}
 )
 
@@ -58,10 +69,57 @@ type PropelApiClient interface {
 	FetchDataPool(ctx context.Context, uniqueName string) (*models.DataPool, error)
 	CreateDeletionJob(ctx context.Context, dataPoolId string, filters []models.FilterInput) (*models.Job, error)
 	FetchDeletionJob(ctx context.Context, id string) (*models.Job, error)
+	CreateAddColumnJob(ctx context.Context, dataPoolId string, columnName string, columnType string) (*models.Job, error)
+	FetchAddColumnJob(ctx context.Context, id string) (*models.Job, error)
 	DeleteDataPool(ctx context.Context, uniqueName string) (string, error)
 	DeleteDataSource(ctx context.Context, uniqueName string) (string, error)
 }
 
+// streamKey identifies a stream of the catalog by its namespace and name.
+type streamKey struct {
+	namespace string
+	name      string
+}
+
+// plannedStream is a stream of the catalog, or a child stream of one of its arrays, shaped as it will be written.
+type plannedStream struct {
+	configuredStream     airbyte.ConfiguredStream
+	streamName           string
+	dataSourceUniqueName string
+	// userManaged is set when the stream is written to an existing Data Source the connector must never alter.
+	userManaged bool
+	flattener   *flattener
+	mapper      *columnMapper
+	transformer *transformer
+	expressions *recordExpressions
+	// discriminator is the column recording the stream of each record of a union.
+	discriminator string
+	routing       *RoutingConfig
+	children      []*arrayChild
+	raw           bool
+}
+
+// streamTarget holds the Data Source a stream is written to, along with how its records are shaped.
+type streamTarget struct {
+	dataSource    *models.DataSource
+	cursorVersion *cursorVersion
+	flattener     *flattener
+	mapper        *columnMapper
+	transformer   *transformer
+	expressions   *recordExpressions
+	coercer       *recordCoercer
+	metadata      *syncMetadata
+	// discriminator is the column recording the stream of each record of a union, set before anything else reads it.
+	discriminator string
+	// router routes the records of the stream to the targets of their routing value. Routed stream targets have no
+	// Data Source of their own, and shape records through the routed targets.
+	router *router
+	// children fan the elements of normalized arrays out into the targets of child streams.
+	children []*arrayChild
+	// raw wraps the whole record data into the _airbyte_data column.
+	raw bool
+}
+
 type Destination struct {
 	logger        airbyte.Logger
 	oauthClient   PropelOAuthClient
@@ -99,7 +157,7 @@ func (d *Destination) Spec() *airbyte.ConnectorSpecification {
 		ConnectionSpecification: airbyte.ConnectionSpecification{
 			Title:    "Propel Destination Spec",
 			Type:     "object",
-			Required: []string{"application_id", "application_secret"},
+			Required: []string{"application_id", "application_secret", "connection_id"},
 			Properties: airbyte.Properties{
 				Properties: map[string]airbyte.PropertySpec{
 					"application_id": {
@@ -122,6 +180,201 @@ func (d *Destination) Spec() *airbyte.ConnectorSpecification {
 						},
 						IsSecret: true,
 					},
+					"data_source_drift_policy": {
+						Title:       "Data Source drift policy",
+						Description: "What to do when an existing Data Source no longer matches its stream primary key, cursor or columns: fail the sync, or recreate the Data Source and Data Pool (a full refresh is then needed).",
+						Default:     string(DriftPolicyFail),
+						Enum:        []any{string(DriftPolicyFail), string(DriftPolicyRecreate)},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
+					"connection_id": {
+						Title:       "Connection ID",
+						Description: "Identifier of this connection, distinct from those of other connections, recorded on the Data Sources it creates. Data from Data Sources created by other connections is never deleted.",
+						Examples:    []string{"production_postgres"},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
+					"allow_unowned_destructive_operations": {
+						Title:       "Allow destructive operations on unowned Data Sources",
+						Description: "Allow overwrite syncs, resets and recreations to delete data from Data Sources that were not created by this connection.",
+						Default:     false,
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.Boolean},
+							},
+						},
+					},
+					"destructive_operations": {
+						Title:       "Destructive operations",
+						Description: "Whether overwrite syncs, full resets and Data Source recreations may delete data: allow them, only log what would be deleted (dry run), or fail the sync.",
+						Default:     string(DestructiveOperationsAllow),
+						Enum:        []any{string(DestructiveOperationsAllow), string(DestructiveOperationsDryRun), string(DestructiveOperationsDeny)},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
+					"destructive_operations_allow_list": {
+						Title:       "Destructive operations allow list",
+						Description: "Unique names of the Data Sources destructive operations are restricted to. All Data Sources owned by the connection are allowed when empty.",
+						Items:       map[string]any{"type": "string"},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.Array},
+							},
+						},
+					},
+					"audit_log_path": {
+						Title:       "Audit log path",
+						Description: "Local file every destructive action is appended to as a JSON line, besides the Airbyte logs.",
+						Examples:    []string{"/local/propel_audit.jsonl"},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
+					"column_type_overrides": {
+						Title:       "Column type overrides",
+						Description: "Propel types of columns, keyed by stream name and then by column name or property path, with nested properties separated by dots.",
+						Examples:    []string{`{"shop_orders": {"customer_id": "STRING", "address.updated_at": "TIMESTAMP"}}`},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.Object},
+							},
+						},
+					},
+					"streams": {
+						Title:       "Stream settings",
+						Description: "Settings per stream, keyed by stream name prefixed by its namespace and an underscore when it has one. flatten_depth expands nested objects into columns up to that depth, joining names with flatten_separator, \"_\" by default, keep_flattened_objects keeps the objects as JSON columns too, normalize_arrays lists the array properties written to child Data Sources, raw stores whole records in a single _airbyte_data JSON column, data_source writes the stream to an existing Data Source the connector never alters, columns renames, excludes and orders properties and adds constant columns, transforms hashes, masks, tokenizes or drops columns holding personal data, filter keeps the records an expression is true for, computed_columns adds columns derived from records by expressions, and routing splits records across Data Sources by the value of a field.",
+						Examples:    []string{`{"shop_orders": {"flatten_depth": 1}}`},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.Object},
+							},
+						},
+					},
+					"unions": {
+						Title:       "Unions",
+						Description: "Streams written to a single Data Source, keyed by union name. Each union lists its streams, which must share their sync mode, primary key and cursor, and records the stream of each record in its discriminator_column, _airbyte_source_stream by default. Stream settings of a union are keyed by its name.",
+						Examples:    []string{`{"orders": {"streams": ["orders_eu", "orders_us"]}}`},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.Object},
+							},
+						},
+					},
+					"schema_change_policy": {
+						Title:       "Schema change policy",
+						Description: "How stream schema changes are applied to existing Data Sources: add new properties as columns, ignore changes, or fail the sync.",
+						Default:     string(SchemaChangePolicyAdd),
+						Enum:        []any{string(SchemaChangePolicyAdd), string(SchemaChangePolicyIgnore), string(SchemaChangePolicyFail)},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
+					"source_timezone": {
+						Title:       "Source timezone",
+						Description: "IANA timezone of timestamps without an offset, e.g. America/New_York. All timestamps are converted to UTC.",
+						Default:     "UTC",
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
+					"narrow_types": {
+						Title:       "Narrow types",
+						Description: "Use narrower column types, such as INT8, INT16, INT32 or FLOAT, when the stream JSON Schema constraints guarantee every value fits.",
+						Default:     false,
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.Boolean},
+							},
+						},
+					},
+					"ignore_required": {
+						Title:       "Ignore required properties",
+						Description: "Create nullable columns for the properties the stream JSON Schema requires. Primary key and cursor columns are never nullable.",
+						Default:     false,
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.Boolean},
+							},
+						},
+					},
+					"pii_salt": {
+						Title:       "PII salt",
+						Description: "Secret salt of the hash and tokenize column transforms of the streams. Changing it changes every hash and token.",
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+						IsSecret: true,
+					},
+					"metadata_columns": {
+						Title:       "Metadata columns",
+						Description: "Optional columns added to the Data Sources besides _airbyte_raw_id, _airbyte_extracted_at and _airbyte_meta: loaded_at adds _airbyte_loaded_at, the time the batch of the record was published, sync_id adds _airbyte_sync_id, stream and namespace add _airbyte_stream and _airbyte_namespace, and connection_label adds _airbyte_connection_label.",
+						Items:       map[string]any{"type": "string", "enum": []string{string(MetadataLoadedAt), string(MetadataSyncID), string(MetadataStream), string(MetadataNamespace), string(MetadataConnectionLabel)}},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.Array},
+							},
+						},
+					},
+					"connection_label": {
+						Title:       "Connection label",
+						Description: "Label of the connection stored in the _airbyte_connection_label metadata column.",
+						Examples:    []string{"shop-production"},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
+					"data_source_name_template": {
+						Title:       "Data Source name template",
+						Description: "Name of the Data Source each stream is written to. The {{prefix}}, {{namespace}} and {{stream}} placeholders are replaced, leaving out empty ones.",
+						Default:     defaultDataSourceNameTemplate,
+						Examples:    []string{"{{prefix}}_{{stream}}"},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
+					"data_source_name_prefix": {
+						Title:       "Data Source name prefix",
+						Description: "Value of the {{prefix}} placeholder of the Data Source name template, e.g. to keep the Data Sources of several environments apart.",
+						Examples:    []string{"staging"},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
+					"time_storage": {
+						Title:       "Time storage",
+						Description: "How time-of-day values are stored: as strings, or as seconds since midnight in UTC.",
+						Default:     string(TimeStorageString),
+						Enum:        []any{string(TimeStorageString), string(TimeStorageSeconds)},
+						PropertyType: airbyte.PropertyType{
+							TypeSet: &airbyte.PropTypes{
+								Types: []airbyte.PropType{airbyte.String},
+							},
+						},
+					},
 				},
 			},
 		},
@@ -140,6 +393,14 @@ func (d *Destination) Check(dstCfgPath string) *airbyte.ConnectionStatus {
 		}
 	}
 
+	if err := dstCfg.Validate(); err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Configuration is invalid: %v", err))
+		return &airbyte.ConnectionStatus{
+			Status:  airbyte.CheckStatusFailed,
+			Message: fmt.Sprintf("Configuration for Propel is invalid: %v", err),
+		}
+	}
+
 	_, err := d.oauthClient.OAuthToken(context.Background(), dstCfg.ApplicationID, dstCfg.ApplicationSecret)
 	if err != nil {
 		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Access token request failed: %v", err))
@@ -164,12 +425,22 @@ func (d *Destination) Write(ctx context.Context, dstCfgPath string, cfgCatalogPa
 		return fmt.Errorf("configuration for Propel is invalid. Unable to read connector configuration: %w", err)
 	}
 
+	if err := dstCfg.Validate(); err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Configuration is invalid: %v", err))
+		return fmt.Errorf("configuration for Propel is invalid: %w", err)
+	}
+
 	var configuredCatalog airbyte.ConfiguredCatalog
 	if err := UnmarshalFromPath(cfgCatalogPath, &configuredCatalog); err != nil {
 		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Configured catalog is invalid: %v", err))
 		return fmt.Errorf("configured catalog is invalid. Unable to parse it %w", err)
 	}
 
+	if err := resolveCatalogSchemas(&configuredCatalog); err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Configured catalog is invalid: %v", err))
+		return fmt.Errorf("configured catalog is invalid: %w", err)
+	}
+
 	oauthToken, err := d.oauthClient.OAuthToken(ctx, dstCfg.ApplicationID, dstCfg.ApplicationSecret)
 	if err != nil {
 		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Access token request failed: %v", err))
@@ -177,84 +448,212 @@ func (d *Destination) Write(ctx context.Context, dstCfgPath string, cfgCatalogPa
 	}
 
 	apiClient := newApiClient(oauthToken.AccessToken)
+	syncID := newSyncID(configuredCatalog)
 	dataSources := map[string]*models.DataSource{}
+	// dataSourceNamespaces holds the namespace of the stream written to each Data Source, which tells legacy owners
+	dataSourceNamespaces := map[string]string{}
+	targets := map[streamKey]*streamTarget{}
 	isFullReset := true
 
-	for _, configuredStream := range configuredCatalog.Streams {
-		isFullReset = isFullReset && configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeOverwrite
-		dataSourceUniqueName := getDataSourceUniqueName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)
+	streams, unionKeys, err := unionStreams(configuredCatalog.Streams, dstCfg.Unions)
+	if err != nil {
+		d.traceConfigError(fmt.Sprintf("Streams of the catalog can't be unioned: %v", err))
+		return fmt.Errorf("failed to union streams: %w", err)
+	}
 
-		dataSource, fetchDataSourceErr := apiClient.FetchDataSource(ctx, dataSourceUniqueName)
-		if fetchDataSourceErr != nil {
-			if !client.NotFoundError("Data Source", fetchDataSourceErr) {
-				d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Data Source %q failed: %v", dataSourceUniqueName, err))
-				return fmt.Errorf("failed to get Data Source: %w", err)
-			}
+	// child streams of normalized arrays are appended to the streams, so they are set up like any other stream
+	planned := make([]plannedStream, 0, len(streams))
+	// arrayTransforms holds the transforms child streams get from the transforms of their parent arrays
+	arrayTransforms := map[streamKey]map[string]Transform{}
+	for i := 0; i < len(streams); i++ {
+		configuredStream := streams[i]
+		streamName := getStreamName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)
+		streamConfig := dstCfg.streamConfig(streamName)
+
+		if transforms, ok := arrayTransforms[streamKey{namespace: configuredStream.Stream.Namespace, name: configuredStream.Stream.Name}]; ok {
+			// the transforms of the child stream itself come first
+			transforms = maps.Clone(transforms)
+			maps.Copy(transforms, streamConfig.Transforms)
+			streamConfig.Transforms = transforms
+		}
 
-			dataSource, err = d.buildAndCreateDataSource(ctx, configuredStream, dataSourceUniqueName, apiClient)
-			if err != nil {
-				return err
-			}
-		} else if configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeOverwrite {
-			dataPool, err := apiClient.FetchDataPool(ctx, dataSourceUniqueName)
-			if err != nil {
-				d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Data Pool %q failed: %v", dataSourceUniqueName, err))
-				return fmt.Errorf("failed to get Data Pool: %w", err)
+		discriminatorColumn := ""
+		if union, ok := dstCfg.Unions[streamName]; ok {
+			discriminatorColumn = union.discriminatorColumn()
+		}
+
+		expressions, err := compileRecordExpressions(configuredStream, streamConfig)
+		if err != nil {
+			d.traceConfigError(fmt.Sprintf("Expressions of stream %q are invalid: %v", streamName, err))
+			return fmt.Errorf("invalid expressions of stream %q: %w", streamName, err)
+		}
+
+		if routing := streamConfig.Routing; routing != nil {
+			if _, ok := configuredStream.Stream.JSONSchema.Properties[routing.Field]; !ok {
+				d.traceConfigError(fmt.Sprintf("Routing of stream %q is invalid: field %q is not a property of the stream", streamName, routing.Field))
+				return fmt.Errorf("invalid routing of stream %q: unknown field %q", streamName, routing.Field)
 			}
+		}
 
-			deletionJob, err := apiClient.CreateDeletionJob(ctx, dataPool.ID, []models.FilterInput{{
-				Column:   airbyteExtractedAtColumn,
-				Operator: "LESS_THAN_OR_EQUAL_TO",
-				Value:    ptr(time.Now().UTC().Format(time.RFC3339Nano)),
-			}})
-			if err != nil {
-				d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Deletion Job creation failed: %v", err))
-				return fmt.Errorf("failed to create Deletion Job for Data Pool %q: %w", dataPool.ID, err)
+		if streamConfig.Raw {
+			if configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeAppendDedup {
+				d.logger.Log(airbyte.LogLevelWarn, fmt.Sprintf("Stream %q is written in raw mode, its records will be appended without de-duplication", streamName))
 			}
 
-			deletionJobUpdated, err := client.WaitForState(client.StateChangeOps[models.Job]{
-				Pending: []string{"CREATED", "IN_PROGRESS"},
-				Target:  []string{"SUCCEEDED", "FAILED"},
-				Refresh: func() (*models.Job, string, error) {
-					resp, err := apiClient.FetchDeletionJob(ctx, deletionJob.ID)
-					if err != nil {
-						d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Deletion Job %q failed: %v", deletionJob.ID, err))
-						return nil, "", fmt.Errorf("failed to get Deletion Job: %w", err)
-					}
+			configuredStream = rawStream(configuredStream)
+		}
 
-					return resp, resp.Status, nil
-				},
-				Timeout: 20 * time.Minute,
-				Delay:   3 * time.Second,
+		schema, recordFlattener, err := flattenSchema(configuredStream.Stream.JSONSchema, streamConfig)
+		if err != nil {
+			d.traceConfigError(fmt.Sprintf("Stream %q can't be flattened: %v", streamName, err))
+			return fmt.Errorf("failed to flatten stream %q: %w", streamName, err)
+		}
+
+		configuredStream.Stream.JSONSchema = schema
+
+		configuredStream, err = addComputedColumns(configuredStream, expressions)
+		if err != nil {
+			d.traceConfigError(fmt.Sprintf("Computed columns of stream %q can't be added: %v", streamName, err))
+			return fmt.Errorf("failed to add computed columns of stream %q: %w", streamName, err)
+		}
+
+		children, arrayChildren, err := childStreams(configuredStream, streamConfig)
+		if err != nil {
+			d.traceConfigError(fmt.Sprintf("Arrays of stream %q can't be normalized: %v", streamName, err))
+			return fmt.Errorf("failed to normalize arrays of stream %q: %w", streamName, err)
+		}
+
+		if err := expressions.checkTransforms(transformedProperties(dstCfg, configuredStream, streamConfig.Transforms, recordFlattener, arrayChildren)); err != nil {
+			d.traceConfigError(fmt.Sprintf("Expressions of stream %q are invalid: %v", streamName, err))
+			return fmt.Errorf("invalid expressions of stream %q: %w", streamName, err)
+		}
+
+		streamTransforms, childArrayTransforms, err := childTransforms(streamConfig.Transforms, arrayChildren)
+		if err != nil {
+			d.traceConfigError(fmt.Sprintf("Columns of stream %q can't be transformed: %v", streamName, err))
+			return fmt.Errorf("failed to transform columns of stream %q: %w", streamName, err)
+		}
+
+		maps.Copy(arrayTransforms, childArrayTransforms)
+
+		// normalized arrays are excluded from the stream, once their child streams are set
+		configuredStream, mapper, err := applyColumnMapping(configuredStream, parentColumnMapping(streamConfig.Columns, streamConfig.NormalizeArrays))
+		if err != nil {
+			d.traceConfigError(fmt.Sprintf("Columns of stream %q can't be mapped: %v", streamName, err))
+			return fmt.Errorf("failed to map columns of stream %q: %w", streamName, err)
+		}
+
+		configuredStream, recordTransformer, err := applyTransforms(configuredStream, streamTransforms, dstCfg.PIISalt, recordFlattener)
+		if err != nil {
+			d.traceConfigError(fmt.Sprintf("Columns of stream %q can't be transformed: %v", streamName, err))
+			return fmt.Errorf("failed to transform columns of stream %q: %w", streamName, err)
+		}
+
+		dataSourceUniqueName := dstCfg.dataSourceName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)
+		switch {
+		case streamConfig.DataSource != "":
+			dataSourceUniqueName = streamConfig.DataSource
+		case streamConfig.Routing != nil:
+			// routed Data Sources are named as records are routed
+			dataSourceUniqueName = ""
+		}
+
+		streams = append(streams, children...)
+		planned = append(planned, plannedStream{
+			configuredStream:     configuredStream,
+			streamName:           streamName,
+			dataSourceUniqueName: dataSourceUniqueName,
+			userManaged:          streamConfig.DataSource != "",
+			flattener:            recordFlattener,
+			mapper:               mapper,
+			transformer:          recordTransformer,
+			expressions:          expressions,
+			discriminator:        discriminatorColumn,
+			routing:              streamConfig.Routing,
+			children:             arrayChildren,
+			raw:                  streamConfig.Raw,
+		})
+	}
+
+	if err := checkDataSourceNameCollisions(dstCfg, planned); err != nil {
+		d.traceConfigError(fmt.Sprintf("Data Source names of the catalog collide: %v", err))
+		return fmt.Errorf("data source names collide: %w", err)
+	}
+
+	newTarget := func(stream plannedStream, dataSource *models.DataSource) *streamTarget {
+		coercer := newRecordCoercer(dataSource, stream.configuredStream, dstCfg.location())
+		return &streamTarget{
+			dataSource:    dataSource,
+			cursorVersion: cursorVersionFromDataSource(stream.configuredStream, dataSource),
+			flattener:     stream.flattener,
+			mapper:        stream.mapper,
+			transformer:   stream.transformer,
+			expressions:   stream.expressions,
+			discriminator: stream.discriminator,
+			coercer:       coercer,
+			metadata:      newSyncMetadata(dstCfg, syncID, coercer),
+			children:      stream.children,
+			raw:           stream.raw,
+		}
+	}
+
+	for _, stream := range planned {
+		// routed targets are set up later, by a closure over the stream
+		stream := stream
+		configuredStream := stream.configuredStream
+		key := streamKey{namespace: configuredStream.Stream.Namespace, name: configuredStream.Stream.Name}
+		isFullReset = isFullReset && configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeOverwrite
+
+		if stream.routing != nil {
+			targets[key], err = d.setUpRouter(stream, func(value string) (*streamTarget, error) {
+				dataSourceUniqueName := dstCfg.routedDataSourceName(*stream.routing, configuredStream.Stream.Namespace, configuredStream.Stream.Name, value)
+				dataSource, err := d.setUpDataSource(ctx, dstCfg, configuredStream, dataSourceUniqueName, apiClient)
+				if err != nil {
+					return nil, err
+				}
+
+				dataSources[dataSourceUniqueName] = dataSource
+				dataSourceNamespaces[dataSourceUniqueName] = configuredStream.Stream.Namespace
+
+				// records are filtered and tagged once for the stream, before they are routed
+				routed := newTarget(stream, dataSource)
+				routed.expressions, routed.discriminator, routed.raw = nil, "", false
+
+				return routed, nil
 			})
 			if err != nil {
-				d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Deletion Job %q state transition failed: %v", deletionJob.ID, err))
-				return fmt.Errorf("state transition for deletion job %q failed: %w", deletionJob.ID, err)
-			}
-
-			if deletionJobUpdated.Status == "FAILED" {
-				d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Deletion Job %q failed", deletionJob.ID))
-				return fmt.Errorf("deletion job %q failed", deletionJob.ID)
+				return err
 			}
 
-			d.logger.Log(airbyte.LogLevelDebug, fmt.Sprintf("Deletion Job %q succeeded for Data Pool %q", deletionJob.ID, dataPool.ID))
+			continue
 		}
 
-		dataSources[dataSourceUniqueName] = dataSource
-		uniqueID := dataSource.ConnectionSettings.WebhookConnectionSettings.UniqueID
+		var dataSource *models.DataSource
+		if stream.userManaged {
+			// user-managed Data Sources are left out of the full reset, as they are never deleted
+			dataSource, err = d.fetchManagedDataSource(ctx, dstCfg, configuredStream, stream.dataSourceUniqueName, apiClient)
+			if err != nil {
+				return err
+			}
+		} else {
+			stream.dataSourceUniqueName, err = d.legacyDataSourceName(ctx, dstCfg, configuredStream, stream.dataSourceUniqueName, apiClient)
+			if err != nil {
+				return err
+			}
 
-		if uniqueID == airbyteRawIdColumn && configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeAppendDedup {
-			d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Dedup destination sync mode is not compatible with Data Pool %q unique ID", dataSource.ID))
-			return fmt.Errorf("append_dedup destination sync mode is not compatible with Data Pool %q unique ID", dataSource.ID)
-		}
+			dataSource, err = d.setUpDataSource(ctx, dstCfg, configuredStream, stream.dataSourceUniqueName, apiClient)
+			if err != nil {
+				return err
+			}
 
-		if uniqueID != airbyteRawIdColumn && configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeAppend {
-			d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Append destination sync mode is not compatible with Data Pool %q ORDER BY statement", dataSource.ID))
-			return fmt.Errorf("append destination sync mode is not compatible with Data Pool %q ORDER BY statement", dataSource.ID)
+			dataSources[stream.dataSourceUniqueName] = dataSource
+			dataSourceNamespaces[stream.dataSourceUniqueName] = configuredStream.Stream.Namespace
 		}
+
+		targets[key] = newTarget(stream, dataSource)
 	}
 
-	recordsWritten, err := d.writeRecords(ctx, input, dataSources)
+	recordsWritten, err := d.writeRecords(ctx, input, targets, unionKeys)
 	if err != nil {
 		return err
 	}
@@ -264,66 +663,344 @@ func (d *Destination) Write(ctx context.Context, dstCfgPath string, cfgCatalogPa
 		// Data Sources must then be removed, so they can later be created with the appropriate ORDER BY statement.
 		// This type of syncs set all stream sync modes as "overwrite" and write no records.
 		d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Full reset sync, all Data Pools will be deleted."))
-		return deleteAllDataSources(ctx, apiClient, dataSources)
+		return d.fullReset(ctx, dstCfg, apiClient, dataSources, dataSourceNamespaces)
 	}
 
 	return nil
 }
 
-func (d *Destination) buildAndCreateDataSource(ctx context.Context, configuredStream airbyte.ConfiguredStream, dataSourceUniqueName string, apiClient PropelApiClient) (*models.DataSource, error) {
+// setUpRouter returns the target of a routed stream, whose routed targets are set up as records are routed. Overwrite
+// syncs set up the Data Source of every allowed value first, so that those of values the sync lacks are truncated, and
+// deleted by full resets, too.
+func (d *Destination) setUpRouter(stream plannedStream, setUp func(value string) (*streamTarget, error)) (*streamTarget, error) {
+	r := newRouter(*stream.routing, setUp)
+
+	if stream.configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeOverwrite {
+		for _, value := range stream.routing.AllowList {
+			if _, err := r.target(value); err != nil {
+				d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Routing of stream %q failed: %v", stream.streamName, err))
+				return nil, fmt.Errorf("failed to route stream %q: %w", stream.streamName, err)
+			}
+		}
+	}
+
+	return &streamTarget{
+		router:        r,
+		expressions:   stream.expressions,
+		discriminator: stream.discriminator,
+		raw:           stream.raw,
+	}, nil
+}
+
+// legacyDataSourceName returns the name of the Data Source the stream was written to before Data Source names were
+// sanitized, when no name template nor prefix is configured and a Data Source of that name exists, so that upgraded
+// connections keep writing to it. Otherwise, it returns the sanitized name.
+func (d *Destination) legacyDataSourceName(ctx context.Context, dstCfg Config, configuredStream airbyte.ConfiguredStream, dataSourceUniqueName string, apiClient PropelApiClient) (string, error) {
+	if dstCfg.DataSourceNameTemplate != "" || dstCfg.DataSourceNamePrefix != "" {
+		return dataSourceUniqueName, nil
+	}
+
+	legacyName := getStreamName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)
+	if legacyName == dataSourceUniqueName {
+		return dataSourceUniqueName, nil
+	}
+
+	if _, err := apiClient.FetchDataSource(ctx, legacyName); err != nil {
+		if client.NotFoundError("Data Source", err) {
+			return dataSourceUniqueName, nil
+		}
+
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Data Source %q failed: %v", legacyName, err))
+		return "", fmt.Errorf("failed to get Data Source: %w", err)
+	}
+
+	d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Stream %q keeps writing to Data Source %q, named before Data Source names were sanitized into %q", legacyName, legacyName, dataSourceUniqueName))
+
+	return legacyName, nil
+}
+
+// setUpDataSource returns the Data Source the stream is written to, creating it when missing. Existing Data Sources
+// are reconciled with the stream, and truncated first by overwrite syncs.
+func (d *Destination) setUpDataSource(ctx context.Context, dstCfg Config, configuredStream airbyte.ConfiguredStream, dataSourceUniqueName string, apiClient PropelApiClient) (*models.DataSource, error) {
+	dataSource, err := apiClient.FetchDataSource(ctx, dataSourceUniqueName)
+	if err != nil {
+		if !client.NotFoundError("Data Source", err) {
+			d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Data Source %q failed: %v", dataSourceUniqueName, err))
+			return nil, fmt.Errorf("failed to get Data Source: %w", err)
+		}
+
+		return d.buildAndCreateDataSource(ctx, dstCfg, configuredStream, dataSourceUniqueName, apiClient)
+	}
+
+	dataSource, recreated, err := d.reconcileDataSource(ctx, dstCfg, configuredStream, dataSource, apiClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if recreated || configuredStream.DestinationSyncMode != airbyte.DestinationSyncModeOverwrite {
+		return dataSource, nil
+	}
+
+	allowed, err := d.authorizeDestructiveOperation(dstCfg, dataSource, configuredStream.Stream.Namespace, "overwrite")
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		d.audit(dstCfg, auditEntry{Action: auditActionTruncateDataPool, Resource: dataSourceUniqueName, Status: auditStatusDryRun})
+		return dataSource, nil
+	}
+
+	if err := d.truncateDataPool(ctx, dstCfg, apiClient, dataSourceUniqueName); err != nil {
+		return nil, err
+	}
+
+	return dataSource, nil
+}
+
+// reconcileDataSource checks that an existing Data Source still matches its configured stream. Incompatible Data Sources
+// either fail the sync or, when the drift policy allows it, are recreated along with their Data Pool. Compatible
+// Data Sources get the stream schema changes applied.
+func (d *Destination) reconcileDataSource(ctx context.Context, dstCfg Config, configuredStream airbyte.ConfiguredStream, dataSource *models.DataSource, apiClient PropelApiClient) (*models.DataSource, bool, error) {
+	createDataSourceOpts, err := d.buildDataSourceOpts(dstCfg, configuredStream, dataSource.UniqueName, dataSource)
+	if err != nil {
+		return nil, false, err
+	}
+
+	mismatches := checkDataSourceCompatibility(dataSource, createDataSourceOpts)
+	if len(mismatches) == 0 {
+		if kept := keepBigNumberColumns(dataSource, configuredStream, createDataSourceOpts); len(kept) > 0 {
+			d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Data Source %q keeps the numeric columns %v of big numbers, recreate it to store every digit in STRING columns", dataSource.UniqueName, kept))
+		}
+
+		return dataSource, false, d.evolveSchema(ctx, dstCfg, dataSource, createDataSourceOpts, apiClient)
+	}
+
+	streamName := getStreamName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)
+	report := strings.Join(mismatches, "; ")
+
+	if dstCfg.DriftPolicy != DriftPolicyRecreate {
+		message := fmt.Sprintf("Data Source %q is not compatible with stream %q: %s. Reset the stream or set data_source_drift_policy to %q.", dataSource.UniqueName, streamName, report, DriftPolicyRecreate)
+		d.traceConfigError(message)
+
+		return nil, false, fmt.Errorf("data source %q is not compatible with stream %q: %s", dataSource.UniqueName, streamName, report)
+	}
+
+	allowed, err := d.authorizeDestructiveOperation(dstCfg, dataSource, configuredStream.Stream.Namespace, "recreate")
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !allowed {
+		d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataPool, Resource: dataSource.UniqueName, Status: auditStatusDryRun})
+		d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataSource, Resource: dataSource.UniqueName, Status: auditStatusDryRun})
+		return nil, false, fmt.Errorf("data source %q is not compatible with stream %q and was not recreated in dry run: %s", dataSource.UniqueName, streamName, report)
+	}
+
+	d.logger.Log(airbyte.LogLevelWarn, fmt.Sprintf("Data Source %q is not compatible with stream %q and will be recreated: %s", dataSource.UniqueName, streamName, report))
+
+	if err := d.deleteDataSources(ctx, dstCfg, apiClient, map[string]*models.DataSource{dataSource.UniqueName: dataSource}); err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Deletion of Data Source %q failed: %v", dataSource.UniqueName, err))
+		return nil, false, err
+	}
+
+	dataSource, err = d.createDataSource(ctx, apiClient, createDataSourceOpts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	d.logger.Log(airbyte.LogLevelWarn, fmt.Sprintf("Data Source %q was recreated, run a full refresh of stream %q to reload its historical records.", dataSource.UniqueName, streamName))
+
+	return dataSource, true, nil
+}
+
+// fetchManagedDataSource returns the user-managed Data Source the stream is mapped to, once checked that its columns
+// can receive the stream properties. User-managed Data Sources are never created, evolved, truncated nor deleted.
+func (d *Destination) fetchManagedDataSource(ctx context.Context, dstCfg Config, configuredStream airbyte.ConfiguredStream, dataSourceUniqueName string, apiClient PropelApiClient) (*models.DataSource, error) {
+	streamName := getStreamName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)
+
+	dataSource, err := apiClient.FetchDataSource(ctx, dataSourceUniqueName)
+	if err != nil {
+		if client.NotFoundError("Data Source", err) {
+			d.traceConfigError(fmt.Sprintf("Data Source %q of stream %q does not exist.", dataSourceUniqueName, streamName))
+			return nil, fmt.Errorf("data source %q of stream %q does not exist", dataSourceUniqueName, streamName)
+		}
+
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Data Source %q failed: %v", dataSourceUniqueName, err))
+		return nil, fmt.Errorf("failed to get Data Source: %w", err)
+	}
+
+	createDataSourceOpts, err := d.buildDataSourceOpts(dstCfg, configuredStream, dataSourceUniqueName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if mismatches := checkManagedDataSourceColumns(dataSource, createDataSourceOpts); len(mismatches) > 0 {
+		report := strings.Join(mismatches, "; ")
+		d.traceConfigError(fmt.Sprintf("Data Source %q can't receive the records of stream %q: %s.", dataSourceUniqueName, streamName, report))
+
+		return nil, fmt.Errorf("data source %q can't receive the records of stream %q: %s", dataSourceUniqueName, streamName, report)
+	}
+
+	if configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeOverwrite {
+		d.logger.Log(airbyte.LogLevelWarn, fmt.Sprintf("Data Source %q is managed by the user and will not be truncated, the records of stream %q are appended.", dataSourceUniqueName, streamName))
+		d.audit(dstCfg, auditEntry{Action: auditActionTruncateDataPool, Resource: dataSourceUniqueName, Status: auditStatusSkipped})
+	}
+
+	return dataSource, nil
+}
+
+// traceConfigError logs the message and reports it to Airbyte as a configuration error.
+func (d *Destination) traceConfigError(message string) {
+	d.logger.Log(airbyte.LogLevelError, message)
+	d.logger.Trace(&airbyte.TraceMessage{
+		Type:      airbyte.TraceTypeError,
+		EmittedAt: float64(time.Now().UnixMilli()),
+		Error: &airbyte.ErrorTraceMessage{
+			Message:     message,
+			FailureType: airbyte.FailureTypeConfigError,
+		},
+	})
+}
+
+func (d *Destination) buildAndCreateDataSource(ctx context.Context, dstCfg Config, configuredStream airbyte.ConfiguredStream, dataSourceUniqueName string, apiClient PropelApiClient) (*models.DataSource, error) {
 	d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("ConfiguredStream PrimaryKey: %v CursorField: %v DestinationSyncMode: %v, SourceDefinedCursor: %v, DefaultCursorField: %v", configuredStream.PrimaryKey, configuredStream.CursorField, configuredStream.DestinationSyncMode, configuredStream.Stream.SourceDefinedCursor, configuredStream.Stream.DefaultCursorField))
 
+	d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Creating Data Source %q for stream %q", dataSourceUniqueName, getStreamName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)))
+
+	createDataSourceOpts, err := d.buildDataSourceOpts(dstCfg, configuredStream, dataSourceUniqueName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.createDataSource(ctx, apiClient, createDataSourceOpts)
+}
+
+// buildDataSourceOpts returns the columns and table settings of the Data Source for the configured stream. Properties
+// keep the column names of the existing Data Source, which is nil when it is created.
+func (d *Destination) buildDataSourceOpts(dstCfg Config, configuredStream airbyte.ConfiguredStream, dataSourceUniqueName string, existing *models.DataSource) (client.CreateDataSourceOpts, error) {
+
 	// Generates a password of 18 chars length with 2 digits, 2 symbols and uppercase letters.
 	authPassword, err := password.Generate(18, 2, 2, false, false)
 	if err != nil {
 		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Password generation failed: %v", err))
-		return nil, fmt.Errorf("failed to generate Basic auth password for Data Source %q: %w", dataSourceUniqueName, err)
+		return client.CreateDataSourceOpts{}, fmt.Errorf("failed to generate Basic auth password for Data Source %q: %w", dataSourceUniqueName, err)
 	}
 
 	orderByColumns := make([]string, 0, len(configuredStream.PrimaryKey))
 	for _, pk := range configuredStream.PrimaryKey {
 		if len(pk) != 1 {
 			d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Unexpected primary key length %d for Data Source %q", len(pk), dataSourceUniqueName))
-			return nil, fmt.Errorf("unexpected primary key length %d for Data Source %q", len(pk), dataSourceUniqueName)
+			return client.CreateDataSourceOpts{}, fmt.Errorf("unexpected primary key length %d for Data Source %q", len(pk), dataSourceUniqueName)
 		}
 
 		orderByColumns = append(orderByColumns, pk[0])
 	}
 
-	cursorField := airbyteExtractedAtColumn
-	if len(configuredStream.CursorField) > 0 {
-		cursorField = configuredStream.CursorField[0]
+	streamName := getStreamName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)
+
+	// the version only uses a cursor column as is when its overridden type is one ReplacingMergeTree accepts as ver
+	var cursorType *models.PropelType
+	if len(configuredStream.CursorField) == 1 {
+		if override, ok := dstCfg.columnTypeOverride(streamName, configuredStream.CursorField[0]); ok {
+			cursorType = &override
+		}
+	}
+
+	version, err := newCursorVersion(configuredStream, cursorType)
+	if err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Cursor version resolution failed for Data Source %q: %v", dataSourceUniqueName, err))
+		return client.CreateDataSourceOpts{}, fmt.Errorf("failed to resolve cursor version: %w", err)
+	}
+
+	if kept := existingCursorVersion(configuredStream, existing); kept != nil && (kept.column != version.column || kept.columnType != version.columnType) {
+		d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Data Source %q keeps its version column %q of type %s", dataSourceUniqueName, existing.ConnectionSettings.WebhookConnectionSettings.TableSettings.Engine.ReplacingMergeTreeTableEngine.Ver, kept.columnType.String()))
+		version = kept
+	}
+
+	propertyNames := make([]string, 0, len(configuredStream.Stream.JSONSchema.Properties))
+	for propertyName := range configuredStream.Stream.JSONSchema.Properties {
+		propertyNames = append(propertyNames, propertyName)
+	}
+	columnMapping := dstCfg.streamConfig(streamName).Columns
+	slices.Sort(propertyNames)
+	slices.SortStableFunc(propertyNames, func(a, b string) int {
+		return orderRank(columnMapping.Order, a) - orderRank(columnMapping.Order, b)
+	})
+
+	names := columnNames(propertyNames, columnMapping.Rename, existingColumnNames(existing))
+	for _, propertyName := range propertyNames {
+		if names[propertyName] != propertyName {
+			d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Property %q of stream %q is stored in column %q", propertyName, streamName, names[propertyName]))
+		}
+	}
+
+	airbyteColumns := dstCfg.airbyteColumns()
+	if dstCfg.streamConfig(streamName).Raw {
+		airbyteColumns = rawAirbyteColumns()
 	}
 
-	columns := make([]*models.WebhookDataSourceColumnInput, 0, len(configuredStream.Stream.JSONSchema.Properties)+len(defaultAirbyteColumns))
+	columns := make([]*models.WebhookDataSourceColumnInput, 0, len(configuredStream.Stream.JSONSchema.Properties)+len(airbyteColumns))
+
+	for _, propertyName := range propertyNames {
+		propertySpec := configuredStream.Stream.JSONSchema.Properties[propertyName]
+		// sources often list nullable properties as required, which only means they are always present
+		required := !dstCfg.IgnoreRequired && slices.Contains(configuredStream.Stream.JSONSchema.Required, propertyName) && !acceptsNull(propertySpec.PropertyType)
 
-	for propertyName, propertySpec := range configuredStream.Stream.JSONSchema.Properties {
 		columnType, err := ConvertAirbyteTypeToPropelType(propertySpec.PropertyType)
 		if err != nil {
 			d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Airbyte to Propel data type conversion failed for Data Source %q: %v", dataSourceUniqueName, err))
-			return nil, fmt.Errorf("failed to convert Airbyte to Propel data type: %w", err)
+			return client.CreateDataSourceOpts{}, fmt.Errorf("failed to convert Airbyte to Propel data type: %w", err)
+		}
+
+		if computedColumn, ok := dstCfg.streamConfig(streamName).ComputedColumns[propertyName]; ok {
+			columnType, _ = parsePropelType(computedColumn.Type)
+		}
+
+		if dstCfg.TimeStorage == TimeStorageSeconds && isTimeOfDay(propertySpec.PropertyType) {
+			columnType = models.Int32PropelType
+		}
+
+		if dstCfg.NarrowTypes && propertyName != version.column {
+			// the version column keeps its type, as ReplacingMergeTree only accepts some of them as ver
+			columnType = narrowPropelType(columnType, propertySpec)
+		}
+
+		if override, ok := dstCfg.columnTypeOverride(streamName, propertyName); ok {
+			columnType = override
 		}
 
 		columns = append(columns, &models.WebhookDataSourceColumnInput{
-			Name:         propertyName,
+			Name:         names[propertyName],
 			Type:         columnType,
-			Nullable:     !slices.Contains(orderByColumns, propertyName) && propertyName != cursorField,
+			Nullable:     !slices.Contains(orderByColumns, propertyName) && propertyName != version.column && !required,
 			JsonProperty: propertyName,
 		})
 	}
 
+	// the table settings refer to columns, while the primary key and the cursor refer to properties
+	for i, orderByColumn := range orderByColumns {
+		if name, ok := names[orderByColumn]; ok {
+			orderByColumns[i] = name
+		}
+	}
+
+	if name, ok := names[version.column]; ok && len(version.path) > 0 && !version.isDedicated() {
+		version.column = name
+	}
+
 	createDataSourceOpts := client.CreateDataSourceOpts{
 		Name: dataSourceUniqueName,
 		BasicAuth: &models.HttpBasicAuthInput{
-			Username: configuredStream.Stream.Namespace,
+			Username: ownerUsername(dstCfg.ConnectionID),
 			Password: authPassword,
 		},
-		Columns: append(columns, defaultAirbyteColumns...),
+		Columns: append(columns, airbyteColumns...),
 	}
 
 	if len(orderByColumns) == 0 && configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeAppendDedup {
 		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Append Dedup sync mode requires at least 1 primary key column"))
-		return nil, fmt.Errorf("no primary keys were found for Data Source %q", dataSourceUniqueName)
+		return client.CreateDataSourceOpts{}, fmt.Errorf("no primary keys were found for Data Source %q", dataSourceUniqueName)
 	}
 
 	if configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeAppend || len(orderByColumns) == 0 {
@@ -331,10 +1008,24 @@ func (d *Destination) buildAndCreateDataSource(ctx context.Context, configuredSt
 		createDataSourceOpts.Timestamp = ptr(airbyteExtractedAtColumn)
 		createDataSourceOpts.UniqueID = ptr(airbyteRawIdColumn)
 
-		return d.createDataSource(ctx, apiClient, createDataSourceOpts)
+		return createDataSourceOpts, nil
+	}
+
+	if version.isDedicated() && version.kind == cursorKindUnordered {
+		d.traceConfigError(fmt.Sprintf("Cursor %v of stream %q can't version its records, as it isn't a number, a date or a timestamp whose order a version keeps. Override the type of a top-level cursor with column_type_overrides, or pick another cursor.", version.path, streamName))
+		return client.CreateDataSourceOpts{}, fmt.Errorf("cursor %v of stream %q has no ordered version", version.path, streamName)
 	}
 
 	// Create de-duplicating Data Source by ORDER BY and ver columns
+	if version.isDedicated() {
+		createDataSourceOpts.Columns = append(createDataSourceOpts.Columns, &models.WebhookDataSourceColumnInput{
+			Name:         airbyteCursorVersionColumn,
+			Type:         version.columnType,
+			Nullable:     false,
+			JsonProperty: airbyteCursorVersionColumn,
+		})
+	}
+
 	createDataSourceOpts.UniqueID = ptr(orderByColumns[0])
 	createDataSourceOpts.TableSettings = &models.TableSettingsInput{
 		PrimaryKey:  []string{}, // these must be explicitly empty
@@ -343,12 +1034,12 @@ func (d *Destination) buildAndCreateDataSource(ctx context.Context, configuredSt
 		Engine: &models.TableEngineInput{
 			ReplacingMergeTree: &models.ReplacingMergeTree{
 				Type: models.TableEngineReplacingMergeTree,
-				Ver:  cursorField,
+				Ver:  version.column,
 			},
 		},
 	}
 
-	return d.createDataSource(ctx, apiClient, createDataSourceOpts)
+	return createDataSourceOpts, nil
 }
 
 func (d *Destination) createDataSource(ctx context.Context, apiClient PropelApiClient, createDataSourceOpts client.CreateDataSourceOpts) (*models.DataSource, error) {
@@ -381,33 +1072,107 @@ func (d *Destination) createDataSource(ctx context.Context, apiClient PropelApiC
 	return dataSource, nil
 }
 
-func (d *Destination) writeRecords(ctx context.Context, input io.Reader, dataSources map[string]*models.DataSource) (int, error) {
-	batchByteSizePerDataSource := make(map[string]int, len(dataSources))
+// writeRecords batches the records of each stream for its Data Source. Records of the streams of a union are written
+// to the target of the union, keyed by unionKeys.
+func (d *Destination) writeRecords(ctx context.Context, input io.Reader, targets map[streamKey]*streamTarget, unionKeys map[streamKey]streamKey) (int, error) {
+	batchByteSizePerDataSource := make(map[string]int, len(targets))
 
 	batchedRecordsPerDataSource := make(map[string][]map[string]any)
-	for dataSourceName := range dataSources {
-		batchedRecordsPerDataSource[dataSourceName] = make([]map[string]any, 0)
-		batchByteSizePerDataSource[dataSourceName] = 0
+	for _, target := range dataSourceTargets(targets) {
+		batchedRecordsPerDataSource[target.dataSource.UniqueName] = make([]map[string]any, 0)
+		batchByteSizePerDataSource[target.dataSource.UniqueName] = 0
 	}
 
 	recordIndex := 0
+	// streamRecordIndexes counts the records of each stream, to point at the records that fail
+	streamRecordIndexes := make(map[string]int, len(targets))
+
+	// addRecord shapes the record of the stream for the Data Source of its target and batches it, publishing the batch
+	// first when full. Unioned streams share their target, so the stream is that of the record itself.
+	addRecord := func(target *streamTarget, stream streamKey, streamIndex int, recordMap map[string]any, recordMeta *airbyte.RecordMeta) error {
+		dataSource := target.dataSource
+		streamName := getStreamName(stream.namespace, stream.name)
+
+		if target.cursorVersion != nil {
+			target.cursorVersion.apply(recordMap)
+		}
+
+		// personal data is transformed before flattening, so that objects kept as JSON columns don't hold it either
+		if target.transformer != nil {
+			target.transformer.transform(recordMap)
+		}
+
+		if target.flattener != nil {
+			target.flattener.flattenRecord(recordMap)
+		}
+
+		if target.mapper != nil {
+			recordMap = target.mapper.shape(recordMap)
+		}
+
+		changes := target.coercer.coerce(recordMap)
+		if column, missing := target.coercer.missingValue(recordMap); missing {
+			d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Record %d of stream %q has no value for non-nullable column %q of Data Source %q", streamIndex, streamName, column, dataSource.UniqueName))
+			return fmt.Errorf("record %d of stream %q has no value for non-nullable column %q", streamIndex, streamName, column)
+		}
+
+		if target.coercer.hasColumn(airbyteMetaColumn) {
+			recordMap[airbyteMetaColumn] = getAirbyteMeta(recordMeta, changes)
+		}
+
+		target.metadata.stamp(recordMap, stream, time.Now())
+
+		recordJsonEncoded, err := json.Marshal(recordMap)
+		if err != nil {
+			return fmt.Errorf("failed to encode record for Data Source %q: %w", dataSource.ID, err)
+		}
+
+		recordJsonBytesSize := len(recordJsonEncoded) + 1
+
+		if batchByteSizePerDataSource[dataSource.UniqueName]+recordJsonBytesSize > maxBytesPerBatch || len(batchedRecordsPerDataSource[dataSource.UniqueName]) == maxRecordsBatchSize {
+			eventsInput := &client.PostEventsInput{
+				WebhookURL:   dataSource.ConnectionSettings.WebhookConnectionSettings.WebhookURL,
+				AuthUsername: dataSource.ConnectionSettings.WebhookConnectionSettings.BasicAuth.Username,
+				AuthPassword: dataSource.ConnectionSettings.WebhookConnectionSettings.BasicAuth.Password,
+			}
+
+			d.logger.Log(airbyte.LogLevelDebug, fmt.Sprintf("Max batch size reached for Data Source %q", dataSource.ID))
+			if err := d.publishBatch(ctx, dataSource, eventsInput, batchedRecordsPerDataSource[dataSource.UniqueName], target.metadata); err != nil {
+				return fmt.Errorf("publish batch failed after max batch size was reached for Data Source %q: %w", dataSource.ID, err)
+			}
+
+			batchedRecordsPerDataSource[dataSource.UniqueName] = batchedRecordsPerDataSource[dataSource.UniqueName][:0]
+			batchByteSizePerDataSource[dataSource.UniqueName] = 0
+		}
+
+		batchedRecordsPerDataSource[dataSource.UniqueName] = append(batchedRecordsPerDataSource[dataSource.UniqueName], recordMap)
+		batchByteSizePerDataSource[dataSource.UniqueName] += recordJsonBytesSize
+
+		return nil
+	}
+
 	scanner := bufio.NewScanner(input)
 	for scanner.Scan() {
 		var airbyteMessage airbyte.Message
-		if err := json.Unmarshal(scanner.Bytes(), &airbyteMessage); err != nil {
+		// Numbers are decoded as json.Number, so that big integers and decimals keep their precision
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&airbyteMessage); err != nil {
 			d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Failed to parse record: %v", err))
 			return recordIndex, fmt.Errorf("failed to parse record: %w", err)
 		}
 
 		switch airbyteMessage.Type {
 		case airbyte.MessageTypeState:
-			for dataSourceName, dataSource := range dataSources {
+			for _, target := range dataSourceTargets(targets) {
+				dataSource := target.dataSource
+				dataSourceName := dataSource.UniqueName
 				eventsInput := &client.PostEventsInput{
 					WebhookURL:   dataSource.ConnectionSettings.WebhookConnectionSettings.WebhookURL,
 					AuthUsername: dataSource.ConnectionSettings.WebhookConnectionSettings.BasicAuth.Username,
 					AuthPassword: dataSource.ConnectionSettings.WebhookConnectionSettings.BasicAuth.Password,
 				}
-				if err := d.publishBatch(ctx, dataSource, eventsInput, batchedRecordsPerDataSource[dataSourceName]); err != nil {
+				if err := d.publishBatch(ctx, dataSource, eventsInput, batchedRecordsPerDataSource[dataSourceName], target.metadata); err != nil {
 					return recordIndex, fmt.Errorf("publish batch failed after state message for Data Source %q: %w", dataSource.ID, err)
 				}
 
@@ -419,61 +1184,133 @@ func (d *Destination) writeRecords(ctx context.Context, input io.Reader, dataSou
 
 			d.logger.State(airbyteMessage.State)
 		case airbyte.MessageTypeRecord:
-			recordMap := airbyteMessage.Record.Data
-			recordMap[airbyteRawIdColumn] = getAirbyteRawID(airbyteMessage.Record.Namespace, airbyteMessage.Record.Stream, recordIndex, airbyteMessage.Record.EmittedAt)
-			recordMap[airbyteExtractedAtColumn] = airbyteMessage.Record.EmittedAt
+			streamName := getStreamName(airbyteMessage.Record.Namespace, airbyteMessage.Record.Stream)
+			streamIndex := streamRecordIndexes[streamName]
+			streamRecordIndexes[streamName]++
 
-			dataSource := dataSources[getDataSourceUniqueName(airbyteMessage.Record.Namespace, airbyteMessage.Record.Stream)]
+			key := streamKey{namespace: airbyteMessage.Record.Namespace, name: airbyteMessage.Record.Stream}
+			if unionKey, ok := unionKeys[key]; ok {
+				key = unionKey
+			}
 
-			recordJsonEncoded, err := json.Marshal(recordMap)
-			if err != nil {
-				return recordIndex, fmt.Errorf("failed to encode record for Data Source %q: %w", dataSource.ID, err)
+			target := targets[key]
+			if target.discriminator != "" {
+				airbyteMessage.Record.Data[target.discriminator] = streamName
 			}
 
-			recordJsonBytesSize := len(recordJsonEncoded) + 1
+			recordMap := airbyteMessage.Record.Data
+			if target.raw {
+				recordMap = map[string]any{airbyteDataColumn: airbyteMessage.Record.Data}
+			}
 
-			if batchByteSizePerDataSource[dataSource.UniqueName]+recordJsonBytesSize > maxBytesPerBatch || len(batchedRecordsPerDataSource[dataSource.UniqueName]) == maxRecordsBatchSize {
-				eventsInput := &client.PostEventsInput{
-					WebhookURL:   dataSource.ConnectionSettings.WebhookConnectionSettings.WebhookURL,
-					AuthUsername: dataSource.ConnectionSettings.WebhookConnectionSettings.BasicAuth.Username,
-					AuthPassword: dataSource.ConnectionSettings.WebhookConnectionSettings.BasicAuth.Password,
+			if target.expressions != nil && !target.expressions.apply(airbyteMessage.Record.Data, recordMap) {
+				recordIndex++
+				continue
+			}
+
+			if target.router != nil {
+				routed, ok, err := target.router.route(airbyteMessage.Record.Data)
+				if err != nil {
+					d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Routing record %d of stream %q failed: %v", streamIndex, streamName, err))
+					return recordIndex, fmt.Errorf("failed to route record %d of stream %q: %w", streamIndex, streamName, err)
 				}
 
-				d.logger.Log(airbyte.LogLevelDebug, fmt.Sprintf("Max batch size reached for Data Source %q", dataSource.ID))
-				if err := d.publishBatch(ctx, dataSource, eventsInput, batchedRecordsPerDataSource[dataSource.UniqueName]); err != nil {
-					return recordIndex, fmt.Errorf("publish batch failed after max batch size was reached for Data Source %q: %w", dataSource.ID, err)
+				if !ok {
+					recordIndex++
+					continue
 				}
 
-				batchedRecordsPerDataSource[dataSource.UniqueName] = batchedRecordsPerDataSource[dataSource.UniqueName][:0]
-				batchByteSizePerDataSource[dataSource.UniqueName] = 0
+				target = routed
+			}
+
+			recordMap[airbyteRawIdColumn] = getAirbyteRawID(airbyteMessage.Record.Namespace, airbyteMessage.Record.Stream, recordIndex, airbyteMessage.Record.EmittedAt)
+			recordMap[airbyteExtractedAtColumn] = time.UnixMilli(airbyteMessage.Record.EmittedAt).UTC().Format(time.RFC3339Nano)
+
+			recordStream := streamKey{namespace: airbyteMessage.Record.Namespace, name: airbyteMessage.Record.Stream}
+			if err := addRecord(target, recordStream, streamIndex, recordMap, airbyteMessage.Record.Meta); err != nil {
+				return recordIndex, err
+			}
+
+			for _, child := range target.children {
+				for index, childRecord := range child.childRecords(recordMap) {
+					childRecord[airbyteRawIdColumn] = getAirbyteRawID(airbyteMessage.Record.Namespace, fmt.Sprintf("%s[%d]", child.streamName, index), recordIndex, airbyteMessage.Record.EmittedAt)
+					childRecord[airbyteExtractedAtColumn] = recordMap[airbyteExtractedAtColumn]
+
+					if err := addRecord(targets[child.key], child.key, streamIndex, childRecord, nil); err != nil {
+						return recordIndex, err
+					}
+				}
 			}
 
-			batchedRecordsPerDataSource[dataSource.UniqueName] = append(batchedRecordsPerDataSource[dataSource.UniqueName], recordMap)
-			batchByteSizePerDataSource[dataSource.UniqueName] += recordJsonBytesSize
 			recordIndex++
 		}
 	}
 
-	for dataSourceName, dataSource := range dataSources {
+	for _, target := range dataSourceTargets(targets) {
+		dataSource := target.dataSource
+		dataSourceName := dataSource.UniqueName
 		eventsInput := &client.PostEventsInput{
 			WebhookURL:   dataSource.ConnectionSettings.WebhookConnectionSettings.WebhookURL,
 			AuthUsername: dataSource.ConnectionSettings.WebhookConnectionSettings.BasicAuth.Username,
 			AuthPassword: dataSource.ConnectionSettings.WebhookConnectionSettings.BasicAuth.Password,
 		}
 
-		if err := d.publishBatch(ctx, dataSource, eventsInput, batchedRecordsPerDataSource[dataSourceName]); err != nil {
+		if err := d.publishBatch(ctx, dataSource, eventsInput, batchedRecordsPerDataSource[dataSourceName], target.metadata); err != nil {
 			return recordIndex, fmt.Errorf("publish batch failed for remaining records in Data Source %q: %w", dataSource.ID, err)
 		}
 	}
 
+	for key, target := range targets {
+		if target.router == nil {
+			continue
+		}
+
+		streamName := getStreamName(key.namespace, key.name)
+		if target.expressions != nil && target.expressions.filtered > 0 {
+			d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Stream %q: %d records filtered out", streamName, target.expressions.filtered))
+		}
+
+		for _, line := range target.router.report() {
+			d.logger.Log(airbyte.LogLevelWarn, fmt.Sprintf("Stream %q: %s", streamName, line))
+		}
+	}
+
+	for _, target := range dataSourceTargets(targets) {
+		if target.expressions != nil && target.expressions.filtered > 0 {
+			d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Data Source %q: %d records filtered out", target.dataSource.UniqueName, target.expressions.filtered))
+		}
+
+		for _, line := range target.coercer.report() {
+			d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Data Source %q: %s", target.dataSource.UniqueName, line))
+		}
+	}
+
 	return recordIndex, nil
 }
 
-func (d *Destination) publishBatch(ctx context.Context, dataSource *models.DataSource, eventsInput *client.PostEventsInput, events []map[string]any) error {
+// dataSourceTargets returns the targets records are batched for: those of the streams, and those routed streams set up
+// so far in place of their own.
+func dataSourceTargets(targets map[streamKey]*streamTarget) []*streamTarget {
+	dataSourceTargets := make([]*streamTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.router != nil {
+			dataSourceTargets = append(dataSourceTargets, target.router.routedTargets()...)
+			continue
+		}
+
+		dataSourceTargets = append(dataSourceTargets, target)
+	}
+
+	return dataSourceTargets
+}
+
+func (d *Destination) publishBatch(ctx context.Context, dataSource *models.DataSource, eventsInput *client.PostEventsInput, events []map[string]any, metadata *syncMetadata) error {
 	if len(events) == 0 {
 		return nil
 	}
 
+	metadata.stampLoadedAt(events, time.Now())
+
 	eventsInput.Events = events
 
 	eventErrors, err := d.webhookClient.PostEvents(ctx, eventsInput)
@@ -491,7 +1328,8 @@ func (d *Destination) publishBatch(ctx context.Context, dataSource *models.DataS
 	return nil
 }
 
-func getDataSourceUniqueName(namespace, streamName string) string {
+// getStreamName returns the stream name qualified by its namespace, which streams are keyed by in the configuration.
+func getStreamName(namespace, streamName string) string {
 	if namespace == "" {
 		return streamName
 	}
@@ -512,63 +1350,3 @@ func getAirbyteRawID(namespace, streamName string, recordIndex int, emittedAt in
 func ptr[T any](value T) *T {
 	return &value
 }
-
-func deleteAllDataSources(ctx context.Context, apiClient PropelApiClient, dataSources map[string]*models.DataSource) error {
-	for dataSourceName := range dataSources {
-		if _, err := apiClient.DeleteDataPool(ctx, dataSourceName); err != nil {
-			return fmt.Errorf("failed to delete Data Pool %q: %w", dataSourceName, err)
-		}
-	}
-
-	for dataSourceName := range dataSources {
-		if _, err := client.WaitForState(client.StateChangeOps[models.DataPool]{
-			Pending: []string{"DELETING"},
-			Target:  []string{"DELETED"},
-			Refresh: func() (*models.DataPool, string, error) {
-				resp, err := apiClient.FetchDataPool(ctx, dataSourceName)
-				if err != nil {
-					if client.NotFoundError("Data Pool", err) {
-						return nil, "DELETED", nil
-					}
-
-					return nil, "", fmt.Errorf("failed to get Data Pool: %w", err)
-				}
-
-				return resp, resp.Status, nil
-			},
-			Timeout: 20 * time.Minute,
-			Delay:   3 * time.Second,
-		}); err != nil {
-			return fmt.Errorf(`transition to "DELETED" failed for Data Pool %q: %w`, dataSourceName, err)
-		}
-
-		if _, err := apiClient.DeleteDataSource(ctx, "_comments"); err != nil {
-			return fmt.Errorf("failed to delete Data Source %q: %w", dataSourceName, err)
-		}
-	}
-
-	for dataSourceName := range dataSources {
-		if _, err := client.WaitForState(client.StateChangeOps[models.DataSource]{
-			Pending: []string{"DELETING"},
-			Target:  []string{"DELETED"},
-			Refresh: func() (*models.DataSource, string, error) {
-				resp, err := apiClient.FetchDataSource(ctx, dataSourceName)
-				if err != nil {
-					if client.NotFoundError("Data Source", err) {
-						return nil, "DELETED", nil
-					}
-
-					return nil, "", fmt.Errorf("failed to get Data Source: %w", err)
-				}
-
-				return resp, resp.Status, nil
-			},
-			Timeout: 20 * time.Minute,
-			Delay:   3 * time.Second,
-		}); err != nil {
-			return fmt.Errorf(`transition to "DELETED" failed for Data Source %q: %w`, dataSourceName, err)
-		}
-	}
-
-	return nil
-}
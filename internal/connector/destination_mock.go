@@ -10,18 +10,29 @@ import (
 	"github.com/propeldata/airbyte-destination/internal/airbyte"
 )
 
-const mockAccessToken = "mockAccessToken"
+const (
+	mockAccessToken  = "mockAccessToken"
+	mockConnectionID = "mock_connection"
+)
 
 var (
 	mockOAuthError   error = nil
 	mockWebhookError error = nil
 	mockApiError     error = nil
-	requestCounter   int   = 0
+	// mockAddColumnJobStatus is the status of Add Column Jobs, SUCCEEDED when empty.
+	mockAddColumnJobStatus string = ""
 )
 
 type MockOauthClient struct{}
-type MockWebhookClient struct{}
-type MockApiClient struct{}
+type MockWebhookClient struct {
+	// events are the events posted, in order.
+	events []map[string]any
+}
+type MockApiClient struct {
+	createdDataSources map[string]*models.DataSource
+	deletedDataSources map[string]bool
+	deletedDataPools   map[string]bool
+}
 
 func NewMockDestination(logger airbyte.Logger) *Destination {
 	return &Destination{
@@ -54,21 +65,56 @@ func NewMockWebhookClient() *MockWebhookClient {
 
 var _ PropelWebhookClient = (*MockWebhookClient)(nil)
 
-func (wc *MockWebhookClient) PostEvents(_ context.Context, _ *client.PostEventsInput) ([]error, error) {
+func (wc *MockWebhookClient) PostEvents(_ context.Context, input *client.PostEventsInput) ([]error, error) {
 	if mockWebhookError != nil {
 		return []error{mockWebhookError}, mockWebhookError
 	}
 
+	wc.events = append(wc.events, input.Events...)
+
 	return []error{}, nil
 }
 
 func NewMockApiClient(_ string) *MockApiClient {
-	return &MockApiClient{}
+	return &MockApiClient{
+		createdDataSources: map[string]*models.DataSource{},
+		deletedDataSources: map[string]bool{},
+		deletedDataPools:   map[string]bool{},
+	}
+}
+
+func mockColumns(names ...string) []models.WebhookColumn {
+	columns := make([]models.WebhookColumn, 0, len(names)+len(defaultAirbyteColumns))
+	for _, name := range names {
+		columnType := models.StringPropelType
+		switch name {
+		case "id":
+			columnType = models.Int64PropelType
+		case "updated_at":
+			columnType = models.TimestampPropelType
+		}
+
+		columns = append(columns, models.WebhookColumn{Name: name, Type: columnType, Nullable: true, JsonProperty: name})
+	}
+
+	for _, col := range defaultAirbyteColumns {
+		// existing Data Sources were created before change tracking existed
+		if col.Name == airbyteMetaColumn {
+			continue
+		}
+
+		columns = append(columns, models.WebhookColumn{Name: col.Name, Type: col.Type, Nullable: col.Nullable, JsonProperty: col.JsonProperty})
+	}
+
+	return columns
 }
 
 var _ PropelApiClient = (*MockApiClient)(nil)
 
 func (ac *MockApiClient) CreateDataSource(_ context.Context, opts client.CreateDataSourceOpts) (*models.DataSource, error) {
+	delete(ac.deletedDataSources, opts.Name)
+	delete(ac.deletedDataPools, opts.Name)
+
 	columns := make([]models.WebhookColumn, 0, len(opts.Columns))
 	for _, col := range opts.Columns {
 		columns = append(columns, models.WebhookColumn{
@@ -101,8 +147,9 @@ func (ac *MockApiClient) CreateDataSource(_ context.Context, opts client.CreateD
 		uniqueID = *opts.UniqueID
 	}
 
-	return &models.DataSource{
+	dataSource := &models.DataSource{
 		UniqueName: opts.Name,
+		Status:     "CONNECTED",
 		ConnectionSettings: models.ConnectionSettings{
 			WebhookConnectionSettings: models.WebhookConnectionSettings{
 				WebhookURL: "https://mockURL.com/v1/WHK1234",
@@ -116,7 +163,11 @@ func (ac *MockApiClient) CreateDataSource(_ context.Context, opts client.CreateD
 				TableSettings: tableSettings,
 			},
 		},
-	}, nil
+	}
+
+	ac.createdDataSources[opts.Name] = dataSource
+
+	return dataSource, nil
 }
 
 func (ac *MockApiClient) FetchDataSource(_ context.Context, uniqueName string) (*models.DataSource, error) {
@@ -124,50 +175,61 @@ func (ac *MockApiClient) FetchDataSource(_ context.Context, uniqueName string) (
 		return nil, mockApiError
 	}
 
+	if ac.deletedDataSources[uniqueName] {
+		return nil, graphql.Errors{{
+			Message:    "Data Source not found",
+			Extensions: map[string]interface{}{"code": "NOT_FOUND"},
+		}}
+	}
+
 	switch uniqueName {
-	case "tacos", "airlines":
+	case "tacos", "airlines", "managed_airlines":
 		return &models.DataSource{
 			UniqueName: uniqueName,
 			ID:         "DSO1234567890",
+			Status:     "CONNECTED",
 			ConnectionSettings: models.ConnectionSettings{
 				WebhookConnectionSettings: models.WebhookConnectionSettings{
 					WebhookURL: "url",
 					BasicAuth: &models.HttpBasicAuth{
-						Username: "username",
+						Username: ownerUsername(mockConnectionID),
 						Password: "password",
 					},
+					Columns:  mockColumns("id", "name"),
 					UniqueID: airbyteRawIdColumn,
 				},
 			},
 		}, nil
 	case "deduped stream":
-		if requestCounter > 0 {
-			return &models.DataSource{
-				UniqueName: uniqueName,
-				ID:         "DSO9876543210",
-				Status:     "CONNECTED",
-				ConnectionSettings: models.ConnectionSettings{
-					WebhookConnectionSettings: models.WebhookConnectionSettings{
-						WebhookURL: "url",
-						BasicAuth: &models.HttpBasicAuth{
-							Username: "username",
-							Password: "password",
-						},
-						TableSettings: &models.TableSettings{
-							PrimaryKey:  []string{},
-							PartitionBy: []string{},
-							OrderBy:     []string{"id"},
-							Engine: &models.Engine{ReplacingMergeTreeTableEngine: models.ReplacingMergeTreeTableEngine{
-								Ver: "updated_at",
-							}},
-						},
+		return &models.DataSource{
+			UniqueName: uniqueName,
+			ID:         "DSO9876543210",
+			Status:     "CONNECTED",
+			ConnectionSettings: models.ConnectionSettings{
+				WebhookConnectionSettings: models.WebhookConnectionSettings{
+					WebhookURL: "url",
+					BasicAuth: &models.HttpBasicAuth{
+						Username: ownerUsername(mockConnectionID),
+						Password: "password",
+					},
+					Columns:  mockColumns("id", "name", "updated_at"),
+					UniqueID: "id",
+					TableSettings: &models.TableSettings{
+						PrimaryKey:  []string{},
+						PartitionBy: []string{},
+						OrderBy:     []string{"id"},
+						Engine: &models.Engine{ReplacingMergeTreeTableEngine: models.ReplacingMergeTreeTableEngine{
+							Ver: "updated_at",
+						}},
 					},
 				},
-			}, nil
-		}
+			},
+		}, nil
 	}
 
-	requestCounter += 1
+	if dataSource, ok := ac.createdDataSources[uniqueName]; ok {
+		return dataSource, nil
+	}
 
 	return nil, graphql.Errors{{
 		Message:    "Data Source not found",
@@ -175,18 +237,29 @@ func (ac *MockApiClient) FetchDataSource(_ context.Context, uniqueName string) (
 	}}
 }
 
-func (ac *MockApiClient) DeleteDataSource(_ context.Context, _ string) (string, error) {
+func (ac *MockApiClient) DeleteDataSource(_ context.Context, uniqueName string) (string, error) {
+	ac.deletedDataSources[uniqueName] = true
+
 	return "DSO1234567", nil
 }
 
-func (ac *MockApiClient) FetchDataPool(_ context.Context, _ string) (*models.DataPool, error) {
+func (ac *MockApiClient) FetchDataPool(_ context.Context, uniqueName string) (*models.DataPool, error) {
+	if ac.deletedDataPools[uniqueName] {
+		return nil, graphql.Errors{{
+			Message:    "Data Pool not found",
+			Extensions: map[string]interface{}{"code": "NOT_FOUND"},
+		}}
+	}
+
 	return &models.DataPool{
 		ID:        "DPO1234567890",
 		Timestamp: models.Timestamp{ColumnName: airbyteExtractedAtColumn},
 	}, nil
 }
 
-func (ac *MockApiClient) DeleteDataPool(_ context.Context, _ string) (string, error) {
+func (ac *MockApiClient) DeleteDataPool(_ context.Context, uniqueName string) (string, error) {
+	ac.deletedDataPools[uniqueName] = true
+
 	return "DPO1234567", nil
 }
 
@@ -203,3 +276,31 @@ func (ac *MockApiClient) FetchDeletionJob(_ context.Context, id string) (*models
 		Status: "SUCCEEDED",
 	}, nil
 }
+
+func (ac *MockApiClient) CreateAddColumnJob(_ context.Context, _ string, _ string, _ string) (*models.Job, error) {
+	return &models.Job{
+		ID:     "DPJ0987654321",
+		Status: "CREATED",
+	}, nil
+}
+
+func (ac *MockApiClient) FetchAddColumnJob(_ context.Context, id string) (*models.Job, error) {
+	switch mockAddColumnJobStatus {
+	case "FAILED":
+		return &models.Job{
+			ID:     id,
+			Status: "FAILED",
+			Error:  models.JobError{Message: "mock add column error"},
+		}, nil
+	case "IN_PROGRESS":
+		return &models.Job{
+			ID:     id,
+			Status: "IN_PROGRESS",
+		}, nil
+	}
+
+	return &models.Job{
+		ID:     id,
+		Status: "SUCCEEDED",
+	}, nil
+}
@@ -3,11 +3,15 @@ package connector
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -16,9 +20,63 @@ import (
 )
 
 const (
-	configPath    = "./test_files/config.json"
-	catalogPath   = "./test_files/configured_catalog.json"
-	inputDataPath = "./test_files/input_data.txt"
+	configPath         = "./test_files/config.json"
+	recreateConfigPath = "./test_files/config_recreate.json"
+	invalidConfigPath  = "./test_files/config_invalid.json"
+	schemaIgnorePath   = "./test_files/config_schema_ignore.json"
+	schemaFailPath     = "./test_files/config_schema_fail.json"
+	otherConnection    = "./test_files/config_other_connection.json"
+	allowUnownedPath   = "./test_files/config_allow_unowned.json"
+	noConnectionIDPath = "./test_files/config_missing_connection_id.json"
+	dryRunConfigPath   = "./test_files/config_dry_run.json"
+	denyConfigPath     = "./test_files/config_deny.json"
+	allowListPath      = "./test_files/config_allow_list.json"
+	invalidTimezone    = "./test_files/config_invalid_timezone.json"
+	ignoreRequiredPath = "./test_files/config_ignore_required.json"
+	normalizeArrays    = "./test_files/config_normalize_arrays.json"
+	rawStreamPath      = "./test_files/config_raw.json"
+	invalidRawPath     = "./test_files/config_invalid_raw.json"
+	typeOverridesPath  = "./test_files/config_column_type_overrides.json"
+	ambiguousTypePath  = "./test_files/config_ambiguous_column_type.json"
+	arrayTransformPath = "./test_files/config_array_transforms.json"
+	unknownTransform   = "./test_files/config_unknown_transform.json"
+	rawTransformPath   = "./test_files/config_raw_transforms.json"
+	nameTemplatePath   = "./test_files/config_name_template.json"
+	managedPath        = "./test_files/config_managed_data_source.json"
+	missingManagedPath = "./test_files/config_missing_data_source.json"
+	incompatiblePath   = "./test_files/config_incompatible_data_source.json"
+	columnMappingPath  = "./test_files/config_column_mapping.json"
+	invalidMappingPath = "./test_files/config_invalid_column_mapping.json"
+	transformsPath     = "./test_files/config_transforms.json"
+	missingSaltPath    = "./test_files/config_transforms_without_salt.json"
+	expressionsPath    = "./test_files/config_expressions.json"
+	badExpressionsPath = "./test_files/config_invalid_expressions.json"
+	metadataPath       = "./test_files/config_metadata.json"
+	missingLabelPath   = "./test_files/config_metadata_without_label.json"
+	unionPath          = "./test_files/config_union.json"
+	invalidUnionPath   = "./test_files/config_invalid_union.json"
+	unionMemberPath    = "./test_files/config_union_member_settings.json"
+	unionCatalog       = "./test_files/configured_catalog_union.json"
+	routingPath        = "./test_files/config_routing.json"
+	unlistedRouting    = "./test_files/config_routing_without_allow_list.json"
+	routingCollision   = "./test_files/config_routing_collision.json"
+	routingTransform   = "./test_files/config_routing_transform.json"
+	computedTransform  = "./test_files/config_computed_transform.json"
+	unionInputPath     = "./test_files/input_data_union.txt"
+	invalidTypePath    = "./test_files/config_invalid_column_type.json"
+	catalogPath        = "./test_files/configured_catalog.json"
+	driftCatalogPath   = "./test_files/configured_catalog_drift.json"
+	newColumnCatalog   = "./test_files/configured_catalog_new_column.json"
+	requiredCatalog    = "./test_files/configured_catalog_required.json"
+	arraysCatalog      = "./test_files/configured_catalog_arrays.json"
+	namesCatalog       = "./test_files/configured_catalog_names.json"
+	collisionCatalog   = "./test_files/configured_catalog_collision.json"
+	fullResetCatalog   = "./test_files/configured_catalog_full_reset.json"
+	stringCursorPath   = "./test_files/configured_catalog_string_cursor.json"
+	inputDataPath      = "./test_files/input_data.txt"
+	requiredInputPath  = "./test_files/input_data_required.txt"
+	arraysInputPath    = "./test_files/input_data_arrays.txt"
+	namesInputPath     = "./test_files/input_data_names.txt"
 )
 
 func TestDestination_Spec(t *testing.T) {
@@ -102,7 +160,12 @@ func TestDestination_Write(t *testing.T) {
 		mockOAuthError      error
 		mockWebhookError    error
 		mockApiError        error
-		expectedError       string
+		// mockAddColumnJobStatus is the status Add Column Jobs end up in, and addColumnJobTimeout how long they are awaited.
+		mockAddColumnJobStatus string
+		addColumnJobTimeout    time.Duration
+		// checkEvents asserts the events posted to the Data Sources.
+		checkEvents   func(a *assert.Assertions, events []map[string]any)
+		expectedError string
 	}{
 		{
 			name:          "Invalid config path",
@@ -112,6 +175,30 @@ func TestDestination_Write(t *testing.T) {
 			expectedLogs:  []string{`"level":"ERROR","message":"Configuration is invalid: open invalid/config/path: no such file or directory"`},
 			expectedError: "configuration for Propel is invalid. Unable to read connector configuration",
 		},
+		{
+			name:          "Invalid drift policy",
+			configPath:    invalidConfigPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`"level":"ERROR","message":"Configuration is invalid: invalid data_source_drift_policy \"ignore\"`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Missing connection ID",
+			configPath:    noConnectionIDPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`"level":"ERROR","message":"Configuration is invalid: connection_id is required`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Invalid source timezone",
+			configPath:    invalidTimezone,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`"level":"ERROR","message":"Configuration is invalid: invalid source_timezone \"Mars/Olympus_Mons\"`},
+			expectedError: "configuration for Propel is invalid",
+		},
 		{
 			name:          "Invalid configured catalog path",
 			configPath:    configPath,
@@ -150,6 +237,450 @@ func TestDestination_Write(t *testing.T) {
 			},
 			expectedError: "publish batch failed after state",
 		},
+		{
+			name:          "Incompatible Data Source",
+			configPath:    configPath,
+			catalogPath:   driftCatalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs: []string{
+				`"type":"TRACE"`,
+				`"failure_type":"config_error"`,
+				`unique ID is \"_airbyte_raw_id\", expected \"id\"`,
+				`primary key changed: ORDER BY is [], expected [id]`,
+				`cursor changed: version column is \"\", expected \"_airbyte_extracted_at\"`,
+			},
+			expectedError: `data source "tacos" is not compatible with stream "tacos"`,
+		},
+		{
+			name:          "Incompatible Data Source recreated",
+			configPath:    recreateConfigPath,
+			catalogPath:   driftCatalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs: []string{
+				`Data Source \"tacos\" is not compatible with stream \"tacos\" and will be recreated`,
+				`Data Source \"tacos\" was recreated, run a full refresh of stream \"tacos\"`,
+			},
+		},
+		{
+			name:          "New column added",
+			configPath:    configPath,
+			catalogPath:   newColumnCatalog,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Column \"price\" of type DOUBLE added to Data Pool \"DPO1234567890\"`},
+		},
+		{
+			name:                   "New column job failure",
+			configPath:             configPath,
+			catalogPath:            newColumnCatalog,
+			inputDataPath:          inputDataPath,
+			mockAddColumnJobStatus: "FAILED",
+			expectedLogs:           []string{`Add Column Job \"DPJ0987654321\" failed: mock add column error`},
+			expectedError:          `add column job "DPJ0987654321" failed: mock add column error`,
+		},
+		{
+			name:                   "New column job timeout",
+			configPath:             configPath,
+			catalogPath:            newColumnCatalog,
+			inputDataPath:          inputDataPath,
+			mockAddColumnJobStatus: "IN_PROGRESS",
+			addColumnJobTimeout:    50 * time.Millisecond,
+			expectedLogs:           []string{`Add Column Job \"DPJ0987654321\" state transition failed: timeout waiting for state to change`},
+			expectedError:          `state transition for add column job "DPJ0987654321" failed`,
+		},
+		{
+			name:          "New column ignored",
+			configPath:    schemaIgnorePath,
+			catalogPath:   newColumnCatalog,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Data Source \"tacos\" is missing columns [price], their values will be dropped`},
+		},
+		{
+			name:          "New column fails the sync",
+			configPath:    schemaFailPath,
+			catalogPath:   newColumnCatalog,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`"failure_type":"config_error"`},
+			expectedError: `schema of Data Source "tacos" changed: new columns [price]`,
+		},
+		{
+			name:          "Record missing a required value",
+			configPath:    configPath,
+			catalogPath:   requiredCatalog,
+			inputDataPath: requiredInputPath,
+			expectedLogs:  []string{`Record 1 of stream \"burritos\" has no value for non-nullable column \"sku\" of Data Source \"burritos\"`},
+			expectedError: `record 1 of stream "burritos" has no value for non-nullable column "sku"`,
+		},
+		{
+			name:          "Required properties ignored",
+			configPath:    ignoreRequiredPath,
+			catalogPath:   requiredCatalog,
+			inputDataPath: requiredInputPath,
+			expectedLogs:  []string{"burritos state 1"},
+		},
+		{
+			name:          "Arrays normalized into child Data Sources",
+			configPath:    normalizeArrays,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs: []string{
+				"orders state 1",
+				`Data Source \"orders__line_items\": 1 values of \"quantity\" coerced`,
+			},
+		},
+		{
+			name:          "Property names sanitized",
+			configPath:    configPath,
+			catalogPath:   namesCatalog,
+			inputDataPath: namesInputPath,
+			expectedLogs: []string{
+				`Property \"Order ID\" of stream \"orders\" is stored in column \"Order_ID_2\"`,
+				`Property \"Updated At\" of stream \"orders\" is stored in column \"Updated_At\"`,
+				`Property \"group\" of stream \"orders\" is stored in column \"group_\"`,
+				"orders state 1",
+			},
+		},
+		{
+			name:          "Data Source name template",
+			configPath:    nameTemplatePath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs: []string{
+				`Creating Data Source \"staging_orders\" for stream \"orders\"`,
+				"orders state 1",
+			},
+		},
+		{
+			name:          "Data Source names collide",
+			configPath:    configPath,
+			catalogPath:   collisionCatalog,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`stream \"orders\" of namespace \"public\" and stream \"public_orders\" of namespace \"\" would both be written to Data Source \"public_orders\"`},
+			expectedError: "data source names collide",
+		},
+		{
+			name:          "User-managed Data Source",
+			configPath:    managedPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs: []string{
+				`Data Source \"managed_airlines\" is managed by the user and will not be truncated, the records of stream \"airlines\" are appended.`,
+				`\"action\":\"truncate_data_pool\",\"resource\":\"managed_airlines\",\"status\":\"skipped\"`,
+			},
+		},
+		{
+			name:          "Missing user-managed Data Source",
+			configPath:    missingManagedPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Data Source \"missing_airlines\" of stream \"airlines\" does not exist.`},
+			expectedError: `data source "missing_airlines" of stream "airlines" does not exist`,
+		},
+		{
+			name:          "Incompatible user-managed Data Source",
+			configPath:    incompatiblePath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs:  []string{`Data Source \"managed_airlines\" can't receive the records of stream \"orders\": property \"line_items\" has no column; property \"tags\" has no column.`},
+			expectedError: `data source "managed_airlines" can't receive the records of stream "orders"`,
+		},
+		{
+			name:          "Columns mapped",
+			configPath:    columnMappingPath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs: []string{
+				`Property \"id\" of stream \"orders\" is stored in column \"order_id\"`,
+				"orders state 1",
+			},
+		},
+		{
+			name:          "Primary key excluded",
+			configPath:    invalidMappingPath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs:  []string{`Columns of stream \"orders\" can't be mapped: primary key property \"id\" can't be excluded`},
+			expectedError: `failed to map columns of stream "orders"`,
+		},
+		{
+			name:          "Columns transformed",
+			configPath:    transformsPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Deletion Job \"DPJ1234567890\" succeeded`},
+			checkEvents: func(a *assert.Assertions, events []map[string]any) {
+				names := make([]any, 0)
+				for _, event := range events {
+					if name, ok := event["name"]; ok {
+						names = append(names, name)
+					}
+				}
+
+				// the taco is hashed, while the names of airlines are dropped
+				a.Equal([]any{hashValue("taco", "pepper")}, names)
+			},
+		},
+		{
+			name:          "Array elements transformed",
+			configPath:    arrayTransformPath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs:  []string{"orders state 1"},
+			checkEvents: func(a *assert.Assertions, events []map[string]any) {
+				values, skus := make([]any, 0), make([]any, 0)
+				for _, event := range events {
+					if value, ok := event["value"]; ok {
+						values = append(values, value)
+					}
+
+					if sku, ok := event["sku"]; ok {
+						skus = append(skus, sku)
+					}
+				}
+
+				a.Equal([]any{hashValue("lunch", "pepper")}, values)
+				a.Equal([]any{maskValue("TACO-1"), maskValue("BURRITO-1")}, skus)
+			},
+		},
+		{
+			name:          "Unknown transformed property",
+			configPath:    unknownTransform,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Columns of stream \"tacos\" can't be transformed: transformed property \"email\" is not a property of the stream`},
+			expectedError: `failed to transform columns of stream "tacos"`,
+		},
+		{
+			name:          "Computed column of a transformed property",
+			configPath:    computedTransform,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Expressions of stream \"tacos\" are invalid: computed column \"label\" reads transformed property \"name\", whose values it would store untransformed`},
+			expectedError: `invalid expressions of stream "tacos"`,
+		},
+		{
+			name:          "Raw stream transformed",
+			configPath:    rawTransformPath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs:  []string{`stream \"orders\" in raw mode can't transform columns, as its records are stored whole`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Transform without salt",
+			configPath:    missingSaltPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`transform \"tokenize\" of column \"name\" of stream \"tacos\" requires a pii_salt`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Records filtered and columns computed",
+			configPath:    expressionsPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Data Source \"airlines\": 8 records filtered out`},
+		},
+		{
+			name:          "Filter on unknown property",
+			configPath:    badExpressionsPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Expressions of stream \"airlines\" are invalid: filter: unknown property \"status\"`},
+			expectedError: `invalid expressions of stream "airlines"`,
+		},
+		{
+			name:          "Metadata columns",
+			configPath:    metadataPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs: []string{
+				`Column \"_airbyte_loaded_at\" of type TIMESTAMP added to Data Pool \"DPO1234567890\"`,
+				`Column \"_airbyte_connection_label\" of type STRING added to Data Pool \"DPO1234567890\"`,
+			},
+		},
+		{
+			name:          "Connection label metadata without label",
+			configPath:    missingLabelPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`metadata column \"connection_label\" requires a connection_label`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Streams unioned",
+			configPath:    unionPath,
+			catalogPath:   unionCatalog,
+			inputDataPath: unionInputPath,
+			expectedLogs: []string{
+				`Creating Data Source \"orders\" for stream \"orders\"`,
+				`"stream_descriptor":{"name":"orders_eu"}`,
+				`"stream_descriptor":{"name":"orders_us"}`,
+			},
+			checkEvents: func(a *assert.Assertions, events []map[string]any) {
+				streams := make([]any, 0, len(events))
+				for _, event := range events {
+					streams = append(streams, event[airbyteStreamColumn])
+					a.Nil(event[airbyteNamespaceColumn])
+				}
+
+				a.Equal([]any{"orders_eu", "orders_us", "orders_us"}, streams)
+			},
+		},
+		{
+			name:          "Settings of a unioned stream",
+			configPath:    unionMemberPath,
+			catalogPath:   unionCatalog,
+			inputDataPath: unionInputPath,
+			expectedLogs:  []string{`stream \"orders_us\" of union \"orders\" has settings of its own, which are ignored, key them by the union name instead`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Union named after another stream",
+			configPath:    invalidUnionPath,
+			catalogPath:   unionCatalog,
+			inputDataPath: unionInputPath,
+			expectedLogs:  []string{`Streams of the catalog can't be unioned: union \"orders_eu\" has the name of stream \"orders_eu\", which it doesn't hold`},
+			expectedError: "failed to union streams",
+		},
+		{
+			name:          "Records routed by field value",
+			configPath:    routingPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs: []string{
+				`Creating Data Source \"airlines_delta\" for stream \"airlines\"`,
+				`Creating Data Source \"airlines_united\" for stream \"airlines\"`,
+				`Creating Data Source \"tacos-taco\" for stream \"tacos\"`,
+				`Stream \"airlines\": 54 records of values missing from the routing allow_list were left out`,
+			},
+		},
+		{
+			name:          "Routed stream without allow list",
+			configPath:    unlistedRouting,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`invalid routing of stream \"airlines\": routing needs an allow_list`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Routed Data Source names collide",
+			configPath:    routingCollision,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`values \"delta.air\" and \"delta_air\" of routing field \"name\" of stream \"airlines\" would both be written to Data Source \"airlines_delta_air\"`},
+			expectedError: "data source names collide",
+		},
+		{
+			name:          "Routing field transformed",
+			configPath:    routingTransform,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`routing field \"name\" of stream \"airlines\" can't be transformed, as its values name the Data Sources`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Raw stream",
+			configPath:    rawStreamPath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs: []string{
+				`Stream \"orders\" is written in raw mode, its records will be appended without de-duplication`,
+				"orders state 1",
+			},
+		},
+		{
+			name:          "Raw stream with flattening",
+			configPath:    invalidRawPath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs:  []string{`stream \"orders\" in raw mode can't flatten objects or normalize arrays`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Column type overridden",
+			configPath:    typeOverridesPath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs:  []string{`Data Source \"orders\": 2 values of \"id\" coerced`},
+		},
+		{
+			name:          "Invalid column type override",
+			configPath:    invalidTypePath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs:  []string{`invalid column_type_overrides type \"UUID\" of column \"id\" of stream \"orders\", expected one of BOOLEAN, STRING`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Ambiguous column type overrides",
+			configPath:    ambiguousTypePath,
+			catalogPath:   arraysCatalog,
+			inputDataPath: arraysInputPath,
+			expectedLogs:  []string{`column_type_overrides \"address.zip_code\" and \"address_zip.code\" of stream \"orders\" both override column \"address_zip_code\"`},
+			expectedError: "configuration for Propel is invalid",
+		},
+		{
+			name:          "Overwrite of unowned Data Source refused",
+			configPath:    otherConnection,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Refusing to overwrite Data Source \"airlines\": it was not created by this connection.`},
+			expectedError: `refusing to overwrite Data Source "airlines" not created by this connection`,
+		},
+		{
+			name:          "Overwrite of unowned Data Source allowed",
+			configPath:    allowUnownedPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Deletion Job \"DPJ1234567890\" succeeded`},
+		},
+		{
+			name:          "Overwrite dry run",
+			configPath:    dryRunConfigPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs: []string{
+				`Dry run: Data Source \"airlines\" would be affected by overwrite`,
+				`\"action\":\"truncate_data_pool\",\"resource\":\"airlines\",\"status\":\"dry_run\"`,
+			},
+		},
+		{
+			name:          "Overwrite denied",
+			configPath:    denyConfigPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`"failure_type":"config_error"`},
+			expectedError: `refusing to overwrite Data Source "airlines", destructive operations are denied`,
+		},
+		{
+			name:          "Overwrite outside of allow list",
+			configPath:    allowListPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedError: `refusing to overwrite Data Source "airlines" not in the destructive operations allow list`,
+		},
+		{
+			name:          "Cursor without ordered version",
+			configPath:    configPath,
+			catalogPath:   stringCursorPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Cursor [revision] of stream \"tickets\" can't version its records, as it isn't a number, a date or a timestamp whose order a version keeps.`},
+			expectedError: `cursor [revision] of stream "tickets" has no ordered version`,
+		},
+		{
+			name:          "Legacy cursor version kept",
+			configPath:    configPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Data Source \"deduped stream\" keeps its version column \"updated_at\" of type TIMESTAMP`},
+		},
+		{
+			name:          "Legacy Data Source name kept",
+			configPath:    configPath,
+			catalogPath:   catalogPath,
+			inputDataPath: inputDataPath,
+			expectedLogs:  []string{`Stream \"deduped stream\" keeps writing to Data Source \"deduped stream\", named before Data Source names were sanitized into \"deduped_stream\"`},
+		},
 		{
 			name:                "Successful write - batch per number of records",
 			configPath:          configPath,
@@ -197,6 +728,15 @@ func TestDestination_Write(t *testing.T) {
 			mockOAuthError, mockWebhookError, mockApiError = tt.mockOAuthError, tt.mockWebhookError, tt.mockApiError
 			st.Cleanup(func() { mockOAuthError, mockWebhookError, mockApiError = nil, nil, nil })
 
+			mockAddColumnJobStatus = tt.mockAddColumnJobStatus
+			st.Cleanup(func() { mockAddColumnJobStatus = "" })
+
+			if tt.addColumnJobTimeout > 0 {
+				timeout, delay := addColumnJobTimeout, addColumnJobDelay
+				addColumnJobTimeout, addColumnJobDelay = tt.addColumnJobTimeout, tt.addColumnJobTimeout/5
+				st.Cleanup(func() { addColumnJobTimeout, addColumnJobDelay = timeout, delay })
+			}
+
 			stdoutBuffer := bytes.NewBufferString("")
 			d := NewMockDestination(airbyte.NewLogger(stdoutBuffer))
 
@@ -214,6 +754,10 @@ func TestDestination_Write(t *testing.T) {
 				a.Contains(err.Error(), tt.expectedError)
 			}
 
+			if tt.checkEvents != nil {
+				tt.checkEvents(a, d.webhookClient.(*MockWebhookClient).events)
+			}
+
 			logsOutput := stdoutBuffer.String()
 			a.Contains(logsOutput, `"level":"DEBUG","message":"Write records"`)
 			for _, log := range tt.expectedLogs {
@@ -223,6 +767,47 @@ func TestDestination_Write(t *testing.T) {
 	}
 }
 
+func TestDestination_Write_FullReset(t *testing.T) {
+	c := require.New(t)
+
+	auditLogPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	dstCfgPath := filepath.Join(t.TempDir(), "config.json")
+	c.NoError(os.WriteFile(dstCfgPath, []byte(fmt.Sprintf(`{"application_id": "APP_mock", "application_secret": "secret_mock", "connection_id": "mock_connection", "audit_log_path": %q}`, auditLogPath)), 0o600))
+
+	stdoutBuffer := bytes.NewBufferString("")
+	d := NewMockDestination(airbyte.NewLogger(stdoutBuffer))
+
+	err := d.Write(context.Background(), dstCfgPath, fullResetCatalog, strings.NewReader(""))
+	c.NoError(err)
+	c.Contains(stdoutBuffer.String(), "Full reset sync, all Data Pools will be deleted.")
+
+	auditLog, err := os.ReadFile(auditLogPath)
+	c.NoError(err)
+
+	entries := make([]auditEntry, 0)
+	for _, line := range strings.Split(strings.TrimSpace(string(auditLog)), "\n") {
+		var entry auditEntry
+		c.NoError(json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	// Overwritten Data Pools are truncated first, then every Data Pool and Data Source is deleted
+	resources := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		c.Equal(auditStatusSucceeded, entry.Status)
+		resources = append(resources, entry.Action+" "+entry.Resource)
+	}
+
+	c.ElementsMatch([]string{
+		"truncate_data_pool airlines",
+		"delete_data_pool airlines",
+		"delete_data_source airlines",
+		"truncate_data_pool tacos",
+		"delete_data_pool tacos",
+		"delete_data_source tacos",
+	}, resources)
+}
+
 func TestGetAirbyteRawID(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,238 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/propeldata/go-client"
+	"github.com/propeldata/go-client/models"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+const (
+	auditActionTruncateDataPool = "truncate_data_pool"
+	auditActionDeleteDataPool   = "delete_data_pool"
+	auditActionDeleteDataSource = "delete_data_source"
+
+	auditStatusSucceeded = "succeeded"
+	auditStatusFailed    = "failed"
+	auditStatusDryRun    = "dry_run"
+	auditStatusSkipped   = "skipped"
+)
+
+// auditEntry records a destructive action taken, or skipped, against a Propel resource.
+type auditEntry struct {
+	Time         string `json:"time"`
+	ConnectionID string `json:"connection_id,omitempty"`
+	Action       string `json:"action"`
+	Resource     string `json:"resource"`
+	Status       string `json:"status"`
+	JobID        string `json:"job_id,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// audit writes the entry to the Airbyte log and, when an audit log path is configured, appends it to that file.
+func (d *Destination) audit(dstCfg Config, entry auditEntry) {
+	entry.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	entry.ConnectionID = dstCfg.ConnectionID
+
+	entryJsonEncoded, err := json.Marshal(entry)
+	if err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Failed to encode audit entry: %v", err))
+		return
+	}
+
+	d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Audit: %s", entryJsonEncoded))
+
+	if dstCfg.AuditLogPath == "" {
+		return
+	}
+
+	file, err := os.OpenFile(dstCfg.AuditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Failed to open audit log %q: %v", dstCfg.AuditLogPath, err))
+		return
+	}
+
+	defer file.Close()
+
+	if _, err := file.Write(append(entryJsonEncoded, '\n')); err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Failed to write audit log %q: %v", dstCfg.AuditLogPath, err))
+	}
+}
+
+// authorizeDestructiveOperation decides whether the operation may delete data from the Data Source or its Data Pool.
+// It returns an error when the operation is denied, and false without error when it must only be simulated. The namespace
+// is that of the stream written to the Data Source.
+func (d *Destination) authorizeDestructiveOperation(dstCfg Config, dataSource *models.DataSource, namespace, operation string) (bool, error) {
+	if dstCfg.DestructiveOperations == DestructiveOperationsDeny {
+		d.traceConfigError(fmt.Sprintf("Refusing to %s Data Source %q: destructive_operations is set to %q.", operation, dataSource.UniqueName, DestructiveOperationsDeny))
+		return false, fmt.Errorf("refusing to %s Data Source %q, destructive operations are denied", operation, dataSource.UniqueName)
+	}
+
+	if err := d.checkDestructiveOperation(dstCfg, dataSource, namespace, operation); err != nil {
+		return false, err
+	}
+
+	if len(dstCfg.DestructiveOperationsAllowList) > 0 && !slices.Contains(dstCfg.DestructiveOperationsAllowList, dataSource.UniqueName) {
+		d.traceConfigError(fmt.Sprintf("Refusing to %s Data Source %q: it is not in destructive_operations_allow_list.", operation, dataSource.UniqueName))
+		return false, fmt.Errorf("refusing to %s Data Source %q not in the destructive operations allow list", operation, dataSource.UniqueName)
+	}
+
+	if dstCfg.DestructiveOperations == DestructiveOperationsDryRun {
+		d.logger.Log(airbyte.LogLevelWarn, fmt.Sprintf("Dry run: Data Source %q would be affected by %s", dataSource.UniqueName, operation))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// truncateDataPool deletes all records extracted up to now from the Data Pool, as required by overwrite syncs.
+func (d *Destination) truncateDataPool(ctx context.Context, dstCfg Config, apiClient PropelApiClient, dataPoolUniqueName string) error {
+	dataPool, err := apiClient.FetchDataPool(ctx, dataPoolUniqueName)
+	if err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Data Pool %q failed: %v", dataPoolUniqueName, err))
+		return fmt.Errorf("failed to get Data Pool: %w", err)
+	}
+
+	deletionJob, err := apiClient.CreateDeletionJob(ctx, dataPool.ID, []models.FilterInput{{
+		Column:   airbyteExtractedAtColumn,
+		Operator: "LESS_THAN_OR_EQUAL_TO",
+		Value:    ptr(time.Now().UTC().Format(time.RFC3339Nano)),
+	}})
+	if err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Deletion Job creation failed: %v", err))
+		d.audit(dstCfg, auditEntry{Action: auditActionTruncateDataPool, Resource: dataPoolUniqueName, Status: auditStatusFailed, Error: err.Error()})
+		return fmt.Errorf("failed to create Deletion Job for Data Pool %q: %w", dataPool.ID, err)
+	}
+
+	deletionJobUpdated, err := client.WaitForState(client.StateChangeOps[models.Job]{
+		Pending: []string{"CREATED", "IN_PROGRESS"},
+		Target:  []string{"SUCCEEDED", "FAILED"},
+		Refresh: func() (*models.Job, string, error) {
+			resp, err := apiClient.FetchDeletionJob(ctx, deletionJob.ID)
+			if err != nil {
+				d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Deletion Job %q failed: %v", deletionJob.ID, err))
+				return nil, "", fmt.Errorf("failed to get Deletion Job: %w", err)
+			}
+
+			return resp, resp.Status, nil
+		},
+		Timeout: 20 * time.Minute,
+		Delay:   3 * time.Second,
+	})
+	if err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Deletion Job %q state transition failed: %v", deletionJob.ID, err))
+		d.audit(dstCfg, auditEntry{Action: auditActionTruncateDataPool, Resource: dataPoolUniqueName, Status: auditStatusFailed, JobID: deletionJob.ID, Error: err.Error()})
+		return fmt.Errorf("state transition for deletion job %q failed: %w", deletionJob.ID, err)
+	}
+
+	if deletionJobUpdated.Status == "FAILED" {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Deletion Job %q failed", deletionJob.ID))
+		d.audit(dstCfg, auditEntry{Action: auditActionTruncateDataPool, Resource: dataPoolUniqueName, Status: auditStatusFailed, JobID: deletionJob.ID, Error: deletionJobUpdated.Error.Message})
+		return fmt.Errorf("deletion job %q failed", deletionJob.ID)
+	}
+
+	d.logger.Log(airbyte.LogLevelDebug, fmt.Sprintf("Deletion Job %q succeeded for Data Pool %q", deletionJob.ID, dataPool.ID))
+	d.audit(dstCfg, auditEntry{Action: auditActionTruncateDataPool, Resource: dataPoolUniqueName, Status: auditStatusSucceeded, JobID: deletionJob.ID})
+
+	return nil
+}
+
+// deleteDataSources deletes the Data Pools of the given Data Sources, and then the Data Sources themselves.
+func (d *Destination) deleteDataSources(ctx context.Context, dstCfg Config, apiClient PropelApiClient, dataSources map[string]*models.DataSource) error {
+	for dataSourceName := range dataSources {
+		if _, err := apiClient.DeleteDataPool(ctx, dataSourceName); err != nil {
+			d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataPool, Resource: dataSourceName, Status: auditStatusFailed, Error: err.Error()})
+			return fmt.Errorf("failed to delete Data Pool %q: %w", dataSourceName, err)
+		}
+	}
+
+	for dataSourceName := range dataSources {
+		if _, err := client.WaitForState(client.StateChangeOps[models.DataPool]{
+			Pending: []string{"DELETING"},
+			Target:  []string{"DELETED"},
+			Refresh: func() (*models.DataPool, string, error) {
+				resp, err := apiClient.FetchDataPool(ctx, dataSourceName)
+				if err != nil {
+					if client.NotFoundError("Data Pool", err) {
+						return nil, "DELETED", nil
+					}
+
+					return nil, "", fmt.Errorf("failed to get Data Pool: %w", err)
+				}
+
+				return resp, resp.Status, nil
+			},
+			Timeout: 20 * time.Minute,
+			Delay:   3 * time.Second,
+		}); err != nil {
+			d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataPool, Resource: dataSourceName, Status: auditStatusFailed, Error: err.Error()})
+			return fmt.Errorf(`transition to "DELETED" failed for Data Pool %q: %w`, dataSourceName, err)
+		}
+
+		d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataPool, Resource: dataSourceName, Status: auditStatusSucceeded})
+
+		if _, err := apiClient.DeleteDataSource(ctx, dataSourceName); err != nil {
+			d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataSource, Resource: dataSourceName, Status: auditStatusFailed, Error: err.Error()})
+			return fmt.Errorf("failed to delete Data Source %q: %w", dataSourceName, err)
+		}
+	}
+
+	for dataSourceName := range dataSources {
+		if _, err := client.WaitForState(client.StateChangeOps[models.DataSource]{
+			Pending: []string{"DELETING"},
+			Target:  []string{"DELETED"},
+			Refresh: func() (*models.DataSource, string, error) {
+				resp, err := apiClient.FetchDataSource(ctx, dataSourceName)
+				if err != nil {
+					if client.NotFoundError("Data Source", err) {
+						return nil, "DELETED", nil
+					}
+
+					return nil, "", fmt.Errorf("failed to get Data Source: %w", err)
+				}
+
+				return resp, resp.Status, nil
+			},
+			Timeout: 20 * time.Minute,
+			Delay:   3 * time.Second,
+		}); err != nil {
+			d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataSource, Resource: dataSourceName, Status: auditStatusFailed, Error: err.Error()})
+			return fmt.Errorf(`transition to "DELETED" failed for Data Source %q: %w`, dataSourceName, err)
+		}
+
+		d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataSource, Resource: dataSourceName, Status: auditStatusSucceeded})
+	}
+
+	return nil
+}
+
+// fullReset deletes the Data Sources of the sync, along with their Data Pools, so they are created again by the next
+// sync. Like overwrites, it fails on Data Sources the connection does not own, keyed with their namespace by
+// namespaces.
+func (d *Destination) fullReset(ctx context.Context, dstCfg Config, apiClient PropelApiClient, dataSources map[string]*models.DataSource, namespaces map[string]string) error {
+	deletableDataSources := make(map[string]*models.DataSource, len(dataSources))
+
+	for dataSourceName, dataSource := range dataSources {
+		allowed, err := d.authorizeDestructiveOperation(dstCfg, dataSource, namespaces[dataSourceName], "delete")
+		if err != nil {
+			return err
+		}
+
+		if !allowed {
+			d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataPool, Resource: dataSourceName, Status: auditStatusDryRun})
+			d.audit(dstCfg, auditEntry{Action: auditActionDeleteDataSource, Resource: dataSourceName, Status: auditStatusDryRun})
+			continue
+		}
+
+		deletableDataSources[dataSourceName] = dataSource
+	}
+
+	return d.deleteDataSources(ctx, dstCfg, apiClient, deletableDataSources)
+}
@@ -0,0 +1,695 @@
+package connector
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expression is a compiled filter or computed column expression. Expressions are evaluated against the data of a
+// record, and follow SQL in that operations on null, or on values of mismatched types, yield null.
+type expression interface {
+	evaluate(data map[string]any) any
+}
+
+// compiledExpression is an expression along with the top-level properties it reads.
+type compiledExpression struct {
+	root       expression
+	properties []string
+}
+
+// expressionFunctions maps the name of each function to the number of arguments it takes, or -1 for any number.
+var expressionFunctions = map[string]int{
+	"lower":    1,
+	"upper":    1,
+	"concat":   -1,
+	"coalesce": -1,
+}
+
+// compileExpression parses the expression source. Properties are referenced by name, and nested properties by
+// their path with dots between names, e.g. address.city. Names other than letters, digits and underscores are
+// quoted by backticks, e.g. `order id`.
+func compileExpression(source string) (*compiledExpression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &expressionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if token := p.peek(); token.kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected %q at position %d", token.text, token.position)
+	}
+
+	return &compiledExpression{root: root, properties: p.properties}, nil
+}
+
+// evaluate returns the value of the expression for the record data.
+func (ce *compiledExpression) evaluate(data map[string]any) any {
+	return ce.root.evaluate(data)
+}
+
+// matches reports whether the record data passes the expression used as a filter. Only true passes.
+func (ce *compiledExpression) matches(data map[string]any) bool {
+	return ce.evaluate(data) == true
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdentifier
+	tokenOperator
+)
+
+type expressionToken struct {
+	kind     tokenKind
+	text     string
+	value    any
+	position int
+}
+
+// expressionOperators lists the operators, the longest first so that they are matched greedily.
+var expressionOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ",", "."}
+
+func tokenizeExpression(source string) ([]expressionToken, error) {
+	tokens := make([]expressionToken, 0)
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+
+			text := string(runes[start:i])
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", text, start)
+			}
+
+			tokens = append(tokens, expressionToken{kind: tokenNumber, text: text, value: json.Number(text), position: start})
+		case r == '"' || r == '\'':
+			start := i
+			var builder strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+
+				builder.WriteRune(runes[i])
+			}
+
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+
+			i++
+			tokens = append(tokens, expressionToken{kind: tokenString, text: string(runes[start:i]), value: builder.String(), position: start})
+		case r == '`':
+			start := i
+			end := slices.Index(runes[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated property name at position %d", start)
+			}
+
+			i += end + 2
+			tokens = append(tokens, expressionToken{kind: tokenIdentifier, text: string(runes[start+1 : i-1]), position: start})
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+
+			tokens = append(tokens, expressionToken{kind: tokenIdentifier, text: string(runes[start:i]), position: start})
+		default:
+			operator := ""
+			for _, candidate := range expressionOperators {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					operator = candidate
+					break
+				}
+			}
+
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", string(r), i)
+			}
+
+			tokens = append(tokens, expressionToken{kind: tokenOperator, text: operator, position: i})
+			i += len([]rune(operator))
+		}
+	}
+
+	return append(tokens, expressionToken{kind: tokenEnd, text: "end of expression", position: len(runes)}), nil
+}
+
+// expressionParser is a recursive descent parser, with a method per precedence level from the lowest to the highest.
+type expressionParser struct {
+	tokens     []expressionToken
+	current    int
+	properties []string
+}
+
+func (p *expressionParser) peek() expressionToken {
+	return p.tokens[p.current]
+}
+
+func (p *expressionParser) next() expressionToken {
+	token := p.tokens[p.current]
+	if token.kind != tokenEnd {
+		p.current++
+	}
+
+	return token
+}
+
+// accept consumes the next token when it is one of the operators.
+func (p *expressionParser) accept(operators ...string) (string, bool) {
+	token := p.peek()
+	if token.kind == tokenOperator && slices.Contains(operators, token.text) {
+		p.next()
+		return token.text, true
+	}
+
+	return "", false
+}
+
+func (p *expressionParser) expect(operator string) error {
+	if _, ok := p.accept(operator); !ok {
+		token := p.peek()
+		return fmt.Errorf("expected %q at position %d, found %q", operator, token.position, token.text)
+	}
+
+	return nil
+}
+
+func (p *expressionParser) parseOr() (expression, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *expressionParser) parseAnd() (expression, error) {
+	return p.parseBinary(p.parseComparison, "&&")
+}
+
+func (p *expressionParser) parseComparison() (expression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	operator, ok := p.accept("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	return &binaryExpression{operator: operator, left: left, right: right}, nil
+}
+
+func (p *expressionParser) parseAdditive() (expression, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *expressionParser) parseMultiplicative() (expression, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+// parseBinary parses left-associative operations of the given operators between operands of a higher precedence.
+func (p *expressionParser) parseBinary(parseOperand func() (expression, error), operators ...string) (expression, error) {
+	left, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator, ok := p.accept(operators...)
+		if !ok {
+			return left, nil
+		}
+
+		right, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryExpression{operator: operator, left: left, right: right}
+	}
+}
+
+func (p *expressionParser) parseUnary() (expression, error) {
+	if operator, ok := p.accept("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unaryExpression{operator: operator, operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (expression, error) {
+	token := p.next()
+
+	switch token.kind {
+	case tokenNumber, tokenString:
+		return &literalExpression{value: token.value}, nil
+	case tokenIdentifier:
+		switch token.text {
+		case "true":
+			return &literalExpression{value: true}, nil
+		case "false":
+			return &literalExpression{value: false}, nil
+		case "null":
+			return &literalExpression{value: nil}, nil
+		}
+
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(token)
+		}
+
+		path := []string{token.text}
+		for {
+			if _, ok := p.accept("."); !ok {
+				break
+			}
+
+			name := p.next()
+			if name.kind != tokenIdentifier {
+				return nil, fmt.Errorf("expected a property name at position %d, found %q", name.position, name.text)
+			}
+
+			path = append(path, name.text)
+		}
+
+		if !slices.Contains(p.properties, path[0]) {
+			p.properties = append(p.properties, path[0])
+		}
+
+		return &propertyExpression{path: path}, nil
+	case tokenOperator:
+		if token.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			return inner, p.expect(")")
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", token.text, token.position)
+}
+
+func (p *expressionParser) parseCall(name expressionToken) (expression, error) {
+	arity, ok := expressionFunctions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.position)
+	}
+
+	args := make([]expression, 0)
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, arg)
+
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	if arity >= 0 && len(args) != arity {
+		return nil, fmt.Errorf("function %q takes %d arguments, got %d", name.text, arity, len(args))
+	}
+
+	return &callExpression{function: name.text, args: args}, nil
+}
+
+type literalExpression struct {
+	value any
+}
+
+func (e *literalExpression) evaluate(map[string]any) any {
+	return e.value
+}
+
+type propertyExpression struct {
+	path []string
+}
+
+func (e *propertyExpression) evaluate(data map[string]any) any {
+	value, _ := lookupPath(data, e.path)
+	return value
+}
+
+type unaryExpression struct {
+	operator string
+	operand  expression
+}
+
+func (e *unaryExpression) evaluate(data map[string]any) any {
+	value := e.operand.evaluate(data)
+
+	switch e.operator {
+	case "!":
+		if b, ok := value.(bool); ok {
+			return !b
+		}
+	case "-":
+		if integer, ok := expressionInteger(value); ok {
+			if integer == math.MinInt64 {
+				return nil
+			}
+
+			return -integer
+		}
+
+		if number, ok := expressionNumber(value); ok {
+			return -number
+		}
+	}
+
+	return nil
+}
+
+type binaryExpression struct {
+	operator string
+	left     expression
+	right    expression
+}
+
+func (e *binaryExpression) evaluate(data map[string]any) any {
+	left := e.left.evaluate(data)
+
+	// logical operators short-circuit, and a known result wins over null
+	switch e.operator {
+	case "&&":
+		if left == false {
+			return false
+		}
+
+		right := e.right.evaluate(data)
+		if right == false {
+			return false
+		}
+
+		if left == true && right == true {
+			return true
+		}
+
+		return nil
+	case "||":
+		if left == true {
+			return true
+		}
+
+		right := e.right.evaluate(data)
+		if right == true {
+			return true
+		}
+
+		if left == false && right == false {
+			return false
+		}
+
+		return nil
+	}
+
+	right := e.right.evaluate(data)
+	if left == nil || right == nil {
+		return nil
+	}
+
+	switch e.operator {
+	case "==", "!=":
+		equal, ok := compareValues(left, right)
+		if !ok {
+			return nil
+		}
+
+		return (equal == 0) == (e.operator == "==")
+	case "<", "<=", ">", ">=":
+		order, ok := compareValues(left, right)
+		if !ok {
+			return nil
+		}
+
+		switch e.operator {
+		case "<":
+			return order < 0
+		case "<=":
+			return order <= 0
+		case ">":
+			return order > 0
+		default:
+			return order >= 0
+		}
+	}
+
+	leftString, leftIsString := left.(string)
+	rightString, rightIsString := right.(string)
+	if e.operator == "+" && leftIsString && rightIsString {
+		return leftString + rightString
+	}
+
+	if leftInteger, ok := expressionInteger(left); ok {
+		if rightInteger, ok := expressionInteger(right); ok {
+			return integerArithmetic(e.operator, leftInteger, rightInteger)
+		}
+	}
+
+	leftNumber, leftOk := expressionNumber(left)
+	rightNumber, rightOk := expressionNumber(right)
+	if !leftOk || !rightOk {
+		return nil
+	}
+
+	switch e.operator {
+	case "+":
+		return leftNumber + rightNumber
+	case "-":
+		return leftNumber - rightNumber
+	case "*":
+		return leftNumber * rightNumber
+	case "/":
+		if rightNumber == 0 {
+			return nil
+		}
+
+		return leftNumber / rightNumber
+	case "%":
+		if rightNumber == 0 {
+			return nil
+		}
+
+		// the remainder keeps the fractions of its operands, which are never truncated into a divisor of 0
+		return math.Mod(leftNumber, rightNumber)
+	}
+
+	return nil
+}
+
+type callExpression struct {
+	function string
+	args     []expression
+}
+
+func (e *callExpression) evaluate(data map[string]any) any {
+	values := make([]any, 0, len(e.args))
+	for _, arg := range e.args {
+		values = append(values, arg.evaluate(data))
+	}
+
+	switch e.function {
+	case "lower", "upper":
+		s, ok := values[0].(string)
+		if !ok {
+			return nil
+		}
+
+		if e.function == "lower" {
+			return strings.ToLower(s)
+		}
+
+		return strings.ToUpper(s)
+	case "concat":
+		var builder strings.Builder
+		for _, value := range values {
+			if value == nil {
+				return nil
+			}
+
+			s, _ := coerceString(value)
+			builder.WriteString(s.(string))
+		}
+
+		return builder.String()
+	case "coalesce":
+		for _, value := range values {
+			if value != nil {
+				return value
+			}
+		}
+	}
+
+	return nil
+}
+
+// expressionNumber returns the value as a float64 when it is a number.
+func expressionNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	}
+
+	return 0, false
+}
+
+// expressionInteger returns the value as an int64 when it is an integer that fits one.
+func expressionInteger(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case json.Number:
+		integer, err := v.Int64()
+		return integer, err == nil
+	}
+
+	return 0, false
+}
+
+// integerArithmetic computes the operation exactly on integers, as float64 rounds those beyond 2^53. Results that
+// overflow an int64 are null, and divisions leaving a remainder are computed on floats.
+func integerArithmetic(operator string, left, right int64) any {
+	switch operator {
+	case "+":
+		sum := left + right
+		if (sum > left) != (right > 0) {
+			return nil
+		}
+
+		return sum
+	case "-":
+		difference := left - right
+		if (difference < left) != (right > 0) {
+			return nil
+		}
+
+		return difference
+	case "*":
+		if left == 0 || right == 0 {
+			return int64(0)
+		}
+
+		product := left * right
+		if product/right != left || (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64) {
+			return nil
+		}
+
+		return product
+	case "/":
+		if right == 0 {
+			return nil
+		}
+
+		if left%right == 0 && (left != math.MinInt64 || right != -1) {
+			return left / right
+		}
+
+		return float64(left) / float64(right)
+	case "%":
+		if right == 0 {
+			return nil
+		}
+
+		return left % right
+	}
+
+	return nil
+}
+
+// compareValues orders two numbers, strings or booleans. Integers are compared exactly, as float64 rounds those
+// beyond 2^53. It returns false when the values can't be compared.
+func compareValues(left, right any) (int, bool) {
+	if leftInteger, ok := expressionInteger(left); ok {
+		if rightInteger, ok := expressionInteger(right); ok {
+			return cmp.Compare(leftInteger, rightInteger), true
+		}
+	}
+
+	if leftNumber, ok := expressionNumber(left); ok {
+		rightNumber, ok := expressionNumber(right)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case leftNumber < rightNumber:
+			return -1, true
+		case leftNumber > rightNumber:
+			return 1, true
+		}
+
+		return 0, true
+	}
+
+	switch l := left.(type) {
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), true
+		}
+	case bool:
+		if r, ok := right.(bool); ok {
+			if l == r {
+				return 0, true
+			}
+
+			if !l {
+				return -1, true
+			}
+
+			return 1, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,99 @@
+package connector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileExpression(t *testing.T) {
+	tests := []struct {
+		name               string
+		source             string
+		expectedProperties []string
+		expectedError      string
+	}{
+		{name: "Comparison", source: `status != "test"`, expectedProperties: []string{"status"}},
+		{name: "Nested and quoted properties", source: "address.city == 'Paris' || `order id` > 10", expectedProperties: []string{"address", "order id"}},
+		{name: "Functions", source: `concat(lower(first_name), " ", upper(last_name))`, expectedProperties: []string{"first_name", "last_name"}},
+		{name: "Unknown function", source: `trim(name)`, expectedError: `unknown function "trim" at position 0`},
+		{name: "Wrong number of arguments", source: `lower(name, "x")`, expectedError: `function "lower" takes 1 arguments, got 2`},
+		{name: "Unterminated string", source: `name == "taco`, expectedError: "unterminated string at position 8"},
+		{name: "Trailing tokens", source: `id == 1 2`, expectedError: `unexpected "2" at position 8`},
+		{name: "Unexpected character", source: `id = 1`, expectedError: `unexpected "=" at position 3`},
+		{name: "Missing operand", source: `id ==`, expectedError: `unexpected "end of expression" at position 5`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			compiled, err := compileExpression(tt.source)
+			if tt.expectedError != "" {
+				require.Error(st, err)
+				assert.Contains(st, err.Error(), tt.expectedError)
+				return
+			}
+
+			require.NoError(st, err)
+			assert.ElementsMatch(st, tt.expectedProperties, compiled.properties)
+		})
+	}
+}
+
+func TestCompiledExpression_Evaluate(t *testing.T) {
+	var data map[string]any
+	decoder := json.NewDecoder(strings.NewReader(`{"id": 42, "price": 2.5, "name": "Taco", "status": null, "active": true, "address": {"city": "Paris"}, "big": 9007199254740993}`))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&data))
+
+	tests := []struct {
+		name     string
+		source   string
+		expected any
+	}{
+		{name: "Arithmetic", source: "id * price + 1", expected: float64(106)},
+		{name: "Precedence", source: "-(id % 5) * 2", expected: int64(-4)},
+		{name: "Big integer arithmetic", source: "big + 1", expected: int64(9007199254740994)},
+		{name: "Integer overflow", source: "big * big", expected: nil},
+		{name: "Integer division", source: "id / 2", expected: int64(21)},
+		{name: "Integer division with a remainder", source: "id / 4", expected: float64(10.5)},
+		{name: "Division by zero", source: "id / 0", expected: nil},
+		{name: "Remainder of a fraction", source: "price % 2", expected: float64(0.5)},
+		{name: "Remainder by a fraction", source: "id % 0.5", expected: float64(0)},
+		{name: "Remainder by zero", source: "id % 0", expected: nil},
+		{name: "String concatenation", source: `name + "s"`, expected: "Tacos"},
+		{name: "Concat of mixed types", source: `concat(name, "-", id)`, expected: "Taco-42"},
+		{name: "Nested property", source: `upper(address.city)`, expected: "PARIS"},
+		{name: "Missing property", source: `address.zip`, expected: nil},
+		{name: "Coalesce", source: `coalesce(status, "unknown")`, expected: "unknown"},
+		{name: "Comparison", source: `id >= 42 && lower(name) == "taco"`, expected: true},
+		{name: "Mismatched types", source: `name != 42`, expected: nil},
+		{name: "Big integers", source: `big > 9007199254740992`, expected: true},
+		{name: "Null comparison", source: `status != "test"`, expected: nil},
+		{name: "Null or true", source: `status == "test" || active`, expected: true},
+		{name: "Null and false", source: `status == "test" && !active`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			compiled, err := compileExpression(tt.source)
+			require.NoError(st, err)
+
+			assert.Equal(st, tt.expected, compiled.evaluate(data))
+		})
+	}
+}
+
+func TestCompiledExpression_Matches(t *testing.T) {
+	a := assert.New(t)
+
+	compiled, err := compileExpression(`status != "test"`)
+	require.NoError(t, err)
+
+	a.True(compiled.matches(map[string]any{"status": "live"}))
+	a.False(compiled.matches(map[string]any{"status": "test"}))
+	a.False(compiled.matches(map[string]any{"status": nil}))
+	a.False(compiled.matches(map[string]any{}))
+}
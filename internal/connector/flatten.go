@@ -0,0 +1,140 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+// flattener expands the nested objects of records into top-level properties, as their stream schema was flattened.
+type flattener struct {
+	// objects are the flattened names of the object properties expanded into columns.
+	objects map[string]bool
+	// paths are the paths of the nested properties within records, keyed by their flattened names.
+	paths map[string][]string
+	// separator joins the names of an object property and its nested properties.
+	separator string
+	// keepObjects keeps the expanded objects as JSON properties too.
+	keepObjects bool
+}
+
+// flattenSchema expands the nested properties of object properties into top-level properties, up to the flatten
+// depth of the stream. It returns the flattened schema, along with the flattener of its records, which is nil when
+// nothing is flattened.
+func flattenSchema(schema airbyte.Properties, streamConfig StreamConfig) (airbyte.Properties, *flattener, error) {
+	if streamConfig.FlattenDepth == 0 {
+		return schema, nil, nil
+	}
+
+	f := &flattener{
+		objects:     map[string]bool{},
+		paths:       map[string][]string{},
+		separator:   streamConfig.FlattenSeparator,
+		keepObjects: streamConfig.KeepFlattenedObjects,
+	}
+
+	properties := make(map[string]airbyte.PropertySpec, len(schema.Properties))
+	for propertyName, propertySpec := range schema.Properties {
+		properties[propertyName] = propertySpec
+	}
+
+	for propertyName, propertySpec := range schema.Properties {
+		if err := f.flattenProperty(properties, schema.Properties, propertyName, propertySpec, streamConfig.FlattenDepth); err != nil {
+			return airbyte.Properties{}, nil, err
+		}
+	}
+
+	if len(f.objects) == 0 {
+		return schema, nil, nil
+	}
+
+	return airbyte.Properties{Properties: properties, Required: schema.Required}, f, nil
+}
+
+func (f *flattener) flattenProperty(properties, originalProperties map[string]airbyte.PropertySpec, name string, propertySpec airbyte.PropertySpec, depth int) error {
+	if depth == 0 || !isFlattenableObject(propertySpec) {
+		return nil
+	}
+
+	f.objects[name] = true
+	if !f.keepObjects {
+		delete(properties, name)
+	}
+
+	for nestedName, nestedSpec := range propertySpec.Properties {
+		flattenedName := name + f.separator + nestedName
+		_, isStreamProperty := originalProperties[flattenedName]
+		if _, ok := properties[flattenedName]; ok || isStreamProperty {
+			return fmt.Errorf("flattened property %q collides with another property of the same name", flattenedName)
+		}
+
+		properties[flattenedName] = nestedSpec
+		f.paths[flattenedName] = append(slices.Clone(f.path(name)), nestedName)
+		if err := f.flattenProperty(properties, originalProperties, flattenedName, nestedSpec, depth-1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// path returns the path of the flattened property within records, which is the property itself unless it is nested.
+func (f *flattener) path(name string) []string {
+	if f != nil {
+		if path, ok := f.paths[name]; ok {
+			return path
+		}
+	}
+
+	return []string{name}
+}
+
+// isFlattened reports whether the property is an object expanded into columns.
+func (f *flattener) isFlattened(name string) bool {
+	return f != nil && f.objects[name]
+}
+
+// isFlattenableObject reports whether the property is an object with known nested properties.
+func isFlattenableObject(propertySpec airbyte.PropertySpec) bool {
+	if propertySpec.TypeSet == nil || len(propertySpec.Properties) == 0 {
+		return false
+	}
+
+	return slices.Equal(removeNullType(propertySpec.TypeSet.Types), []airbyte.PropType{airbyte.Object})
+}
+
+// flattenRecord expands the nested objects of the record in place. Missing or null objects leave their nested
+// properties missing.
+func (f *flattener) flattenRecord(recordMap map[string]any) {
+	fields := make([]string, 0, len(f.objects))
+	for field := range recordMap {
+		if f.objects[field] {
+			fields = append(fields, field)
+		}
+	}
+
+	for _, field := range fields {
+		f.flattenValue(recordMap, field, recordMap[field])
+	}
+}
+
+func (f *flattener) flattenValue(recordMap map[string]any, name string, value any) {
+	recordMap[name] = value
+	if !f.objects[name] {
+		return
+	}
+
+	if !f.keepObjects {
+		delete(recordMap, name)
+	}
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+
+	for key, nestedValue := range object {
+		f.flattenValue(recordMap, name+f.separator+key, nestedValue)
+	}
+}
@@ -0,0 +1,146 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func objectSpec(properties map[string]airbyte.PropertySpec) airbyte.PropertySpec {
+	return airbyte.PropertySpec{
+		PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Null, airbyte.Object}}},
+		Properties:   properties,
+	}
+}
+
+func stringSpec() airbyte.PropertySpec {
+	return airbyte.PropertySpec{PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}}}
+}
+
+func TestFlattenSchema(t *testing.T) {
+	schema := airbyte.Properties{
+		Properties: map[string]airbyte.PropertySpec{
+			"id": stringSpec(),
+			"address": objectSpec(map[string]airbyte.PropertySpec{
+				"city": stringSpec(),
+				"geo":  objectSpec(map[string]airbyte.PropertySpec{"lat": stringSpec()}),
+			}),
+			"extra": objectSpec(nil),
+		},
+	}
+
+	tests := []struct {
+		name               string
+		streamConfig       StreamConfig
+		expectedProperties []string
+	}{
+		{
+			name:               "No flattening",
+			streamConfig:       StreamConfig{FlattenSeparator: "_"},
+			expectedProperties: []string{"address", "extra", "id"},
+		},
+		{
+			name:               "One level",
+			streamConfig:       StreamConfig{FlattenDepth: 1, FlattenSeparator: "_"},
+			expectedProperties: []string{"address_city", "address_geo", "extra", "id"},
+		},
+		{
+			name:               "Two levels",
+			streamConfig:       StreamConfig{FlattenDepth: 2, FlattenSeparator: "__"},
+			expectedProperties: []string{"address__city", "address__geo__lat", "extra", "id"},
+		},
+		{
+			name:               "Objects kept",
+			streamConfig:       StreamConfig{FlattenDepth: 2, FlattenSeparator: "_", KeepFlattenedObjects: true},
+			expectedProperties: []string{"address", "address_city", "address_geo", "address_geo_lat", "extra", "id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			a := assert.New(st)
+
+			flattened, _, err := flattenSchema(schema, tt.streamConfig)
+			a.NoError(err)
+
+			properties := make([]string, 0, len(flattened.Properties))
+			for propertyName := range flattened.Properties {
+				properties = append(properties, propertyName)
+			}
+
+			a.ElementsMatch(tt.expectedProperties, properties)
+		})
+	}
+}
+
+func TestFlattenSchema_Collision(t *testing.T) {
+	schema := airbyte.Properties{
+		Properties: map[string]airbyte.PropertySpec{
+			"address":      objectSpec(map[string]airbyte.PropertySpec{"city": stringSpec()}),
+			"address_city": stringSpec(),
+		},
+	}
+
+	_, _, err := flattenSchema(schema, StreamConfig{FlattenDepth: 1, FlattenSeparator: "_"})
+	assert.EqualError(t, err, `flattened property "address_city" collides with another property of the same name`)
+}
+
+func TestFlattener_FlattenRecord(t *testing.T) {
+	schema := airbyte.Properties{
+		Properties: map[string]airbyte.PropertySpec{
+			"address": objectSpec(map[string]airbyte.PropertySpec{
+				"city": stringSpec(),
+				"geo":  objectSpec(map[string]airbyte.PropertySpec{"lat": stringSpec()}),
+			}),
+			"extra": objectSpec(nil),
+		},
+	}
+
+	tests := []struct {
+		name           string
+		streamConfig   StreamConfig
+		record         map[string]any
+		expectedRecord map[string]any
+	}{
+		{
+			name:           "Nested objects",
+			streamConfig:   StreamConfig{FlattenDepth: 2, FlattenSeparator: "_"},
+			record:         map[string]any{"address": map[string]any{"city": "Austin", "geo": map[string]any{"lat": "30.26"}}, "extra": map[string]any{"a": "b"}},
+			expectedRecord: map[string]any{"address_city": "Austin", "address_geo_lat": "30.26", "extra": map[string]any{"a": "b"}},
+		},
+		{
+			name:           "Depth reached",
+			streamConfig:   StreamConfig{FlattenDepth: 1, FlattenSeparator: "_"},
+			record:         map[string]any{"address": map[string]any{"city": "Austin", "geo": map[string]any{"lat": "30.26"}}},
+			expectedRecord: map[string]any{"address_city": "Austin", "address_geo": map[string]any{"lat": "30.26"}},
+		},
+		{
+			name:           "Null object",
+			streamConfig:   StreamConfig{FlattenDepth: 1, FlattenSeparator: "_"},
+			record:         map[string]any{"address": nil},
+			expectedRecord: map[string]any{},
+		},
+		{
+			name:           "Objects kept",
+			streamConfig:   StreamConfig{FlattenDepth: 1, FlattenSeparator: "_", KeepFlattenedObjects: true},
+			record:         map[string]any{"address": map[string]any{"city": "Austin"}},
+			expectedRecord: map[string]any{"address": map[string]any{"city": "Austin"}, "address_city": "Austin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			c := require.New(st)
+
+			_, f, err := flattenSchema(schema, tt.streamConfig)
+			c.NoError(err)
+			c.NotNil(f)
+
+			f.flattenRecord(tt.record)
+			c.Equal(tt.expectedRecord, tt.record)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package connector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+// columnMapper shapes the records of a stream as its column mapping does: excluded properties are left out and
+// constant columns are added.
+type columnMapper struct {
+	exclude   []string
+	constants map[string]any
+}
+
+// applyColumnMapping removes the excluded properties from the stream schema and adds the constant columns to it. It
+// returns the mapped stream, along with the mapper of its records, which is nil when there is no mapping. Primary key
+// and cursor properties can't be excluded.
+func applyColumnMapping(configuredStream airbyte.ConfiguredStream, mapping ColumnMapping) (airbyte.ConfiguredStream, *columnMapper, error) {
+	if len(mapping.Exclude) == 0 && len(mapping.Constants) == 0 {
+		return configuredStream, nil, nil
+	}
+
+	schema := configuredStream.Stream.JSONSchema
+	properties := make(map[string]airbyte.PropertySpec, len(schema.Properties)+len(mapping.Constants))
+	for propertyName, propertySpec := range schema.Properties {
+		properties[propertyName] = propertySpec
+	}
+
+	for _, propertyName := range mapping.Exclude {
+		for _, pk := range configuredStream.PrimaryKey {
+			if slices.Equal(pk, []string{propertyName}) {
+				return configuredStream, nil, fmt.Errorf("primary key property %q can't be excluded", propertyName)
+			}
+		}
+
+		if len(configuredStream.CursorField) > 0 && configuredStream.CursorField[0] == propertyName {
+			return configuredStream, nil, fmt.Errorf("cursor property %q can't be excluded", propertyName)
+		}
+
+		delete(properties, propertyName)
+	}
+
+	for columnName, value := range mapping.Constants {
+		if _, ok := properties[columnName]; ok {
+			return configuredStream, nil, fmt.Errorf("constant column %q collides with a stream property", columnName)
+		}
+
+		properties[columnName] = airbyte.PropertySpec{PropertyType: constantPropertyType(value)}
+	}
+
+	schema.Properties = properties
+	schema.Required = slices.DeleteFunc(slices.Clone(schema.Required), func(propertyName string) bool {
+		return slices.Contains(mapping.Exclude, propertyName)
+	})
+	configuredStream.Stream.JSONSchema = schema
+
+	return configuredStream, &columnMapper{exclude: mapping.Exclude, constants: mapping.Constants}, nil
+}
+
+// constantPropertyType returns the JSON Schema type of a constant column value.
+func constantPropertyType(value any) airbyte.PropertyType {
+	var propType airbyte.PropType
+	switch v := value.(type) {
+	case bool:
+		propType = airbyte.Boolean
+	case float64:
+		propType = airbyte.Number
+		if v == math.Trunc(v) {
+			propType = airbyte.Integer
+		}
+	case json.Number:
+		propType = airbyte.Number
+		if _, err := v.Int64(); err == nil {
+			propType = airbyte.Integer
+		}
+	case string:
+		propType = airbyte.String
+	case []any:
+		propType = airbyte.Array
+	default:
+		propType = airbyte.Object
+	}
+
+	return airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{propType}}}
+}
+
+// shape returns the record as posted to the Data Source. The record itself is left untouched, as the arrays it
+// holds may still be normalized into child streams.
+func (cm *columnMapper) shape(recordMap map[string]any) map[string]any {
+	shaped := make(map[string]any, len(recordMap)+len(cm.constants))
+	for key, value := range recordMap {
+		if !slices.Contains(cm.exclude, key) {
+			shaped[key] = value
+		}
+	}
+
+	for columnName, value := range cm.constants {
+		shaped[columnName] = value
+	}
+
+	return shaped
+}
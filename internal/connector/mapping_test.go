@@ -0,0 +1,73 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestApplyColumnMapping(t *testing.T) {
+	c := require.New(t)
+
+	var configuredCatalog airbyte.ConfiguredCatalog
+	c.NoError(UnmarshalFromPath("./test_files/configured_catalog_arrays.json", &configuredCatalog))
+	configuredStream := configuredCatalog.Streams[0]
+
+	mapped, mapper, err := applyColumnMapping(configuredStream, ColumnMapping{
+		Exclude:   []string{"tags"},
+		Constants: map[string]any{"environment": "production", "version": float64(2)},
+	})
+	c.NoError(err)
+	c.NotNil(mapper)
+	c.NotContains(mapped.Stream.JSONSchema.Properties, "tags")
+	c.Contains(configuredStream.Stream.JSONSchema.Properties, "tags", "the configured stream is left untouched")
+	c.Equal([]airbyte.PropType{airbyte.String}, mapped.Stream.JSONSchema.Properties["environment"].TypeSet.Types)
+	c.Equal([]airbyte.PropType{airbyte.Integer}, mapped.Stream.JSONSchema.Properties["version"].TypeSet.Types)
+
+	record := map[string]any{"id": float64(1), "tags": []any{"lunch"}}
+	c.Equal(map[string]any{"id": float64(1), "environment": "production", "version": float64(2)}, mapper.shape(record))
+	c.Contains(record, "tags")
+
+	_, mapper, err = applyColumnMapping(configuredStream, ColumnMapping{Rename: map[string]string{"id": "order_id"}})
+	c.NoError(err)
+	c.Nil(mapper)
+
+	_, _, err = applyColumnMapping(configuredStream, ColumnMapping{Exclude: []string{"id"}})
+	c.EqualError(err, `primary key property "id" can't be excluded`)
+
+	_, _, err = applyColumnMapping(configuredStream, ColumnMapping{Constants: map[string]any{"tags": "none"}})
+	c.EqualError(err, `constant column "tags" collides with a stream property`)
+}
+
+func TestColumnMapping_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		mapping       ColumnMapping
+		expectedError string
+	}{
+		{name: "Valid mapping", mapping: ColumnMapping{Rename: map[string]string{"id": "order_id"}, Exclude: []string{"tags"}}},
+		{name: "Empty column name", mapping: ColumnMapping{Rename: map[string]string{"id": ""}}, expectedError: `property "id" is renamed to an empty column name`},
+		{name: "Column name with spaces", mapping: ColumnMapping{Rename: map[string]string{"id": "order id"}}, expectedError: `property "id" is renamed to invalid column name "order id", expected up to 64 letters, digits and underscores, starting with no digit and other than a SQL keyword, such as "order_id"`},
+		{name: "Column name with dots", mapping: ColumnMapping{Rename: map[string]string{"id": "a.b"}}, expectedError: `property "id" is renamed to invalid column name "a.b", expected up to 64 letters, digits and underscores, starting with no digit and other than a SQL keyword, such as "a_b"`},
+		{name: "Reserved column name", mapping: ColumnMapping{Rename: map[string]string{"id": "group"}}, expectedError: `property "id" is renamed to invalid column name "group", expected up to 64 letters, digits and underscores, starting with no digit and other than a SQL keyword, such as "group_"`},
+		{name: "Renamed and excluded", mapping: ColumnMapping{Rename: map[string]string{"id": "order_id"}, Exclude: []string{"id"}}, expectedError: `property "id" is both renamed and excluded`},
+		{name: "Same column name", mapping: ColumnMapping{Rename: map[string]string{"a": "total", "b": "Total"}}, expectedError: `properties "a" and "b" are both renamed to column`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			err := tt.mapping.validate()
+			if tt.expectedError == "" {
+				assert.NoError(st, err)
+				return
+			}
+
+			if assert.Error(st, err) {
+				assert.Contains(st, err.Error(), tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package connector
+
+import (
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+// getAirbyteMeta returns the _airbyte_meta value of a record: the changes reported by the source, followed by the
+// changes made by the connector. Only changes losing data are tracked, as the Airbyte protocol defines no others:
+// values converted to their column type without loss, and invalid values left untouched, are only counted in the logs.
+func getAirbyteMeta(recordMeta *airbyte.RecordMeta, changes []valueChange) airbyte.RecordMeta {
+	meta := airbyte.RecordMeta{Changes: make([]airbyte.RecordChange, 0, len(changes))}
+	if recordMeta != nil {
+		meta.Changes = append(meta.Changes, recordMeta.Changes...)
+	}
+
+	for _, change := range changes {
+		switch change.change {
+		case valueChangeNulled:
+			meta.Changes = append(meta.Changes, airbyte.RecordChange{Field: change.field, Change: airbyte.RecordChangeNulled, Reason: airbyte.RecordChangeReasonDestinationTypecastError})
+		case valueChangeTruncated:
+			meta.Changes = append(meta.Changes, airbyte.RecordChange{Field: change.field, Change: airbyte.RecordChangeTruncated, Reason: airbyte.RecordChangeReasonDestinationFieldSizeLimitation})
+		}
+	}
+
+	return meta
+}
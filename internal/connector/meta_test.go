@@ -0,0 +1,51 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestGetAirbyteMeta(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordMeta *airbyte.RecordMeta
+		changes    []valueChange
+		expected   airbyte.RecordMeta
+	}{
+		{
+			name:     "No changes",
+			expected: airbyte.RecordMeta{Changes: []airbyte.RecordChange{}},
+		},
+		{
+			name: "Source changes are kept",
+			recordMeta: &airbyte.RecordMeta{Changes: []airbyte.RecordChange{
+				{Field: "description", Change: airbyte.RecordChangeTruncated, Reason: airbyte.RecordChangeReasonSourceFieldSizeLimitation},
+			}},
+			changes: []valueChange{{field: "price", change: valueChangeNulled}},
+			expected: airbyte.RecordMeta{Changes: []airbyte.RecordChange{
+				{Field: "description", Change: airbyte.RecordChangeTruncated, Reason: airbyte.RecordChangeReasonSourceFieldSizeLimitation},
+				{Field: "price", Change: airbyte.RecordChangeNulled, Reason: airbyte.RecordChangeReasonDestinationTypecastError},
+			}},
+		},
+		{
+			name: "Connector changes",
+			changes: []valueChange{
+				{field: "active", change: valueChangeCoerced},
+				{field: "name", change: valueChangeTruncated},
+				{field: "id", change: valueChangeInvalid},
+			},
+			expected: airbyte.RecordMeta{Changes: []airbyte.RecordChange{
+				{Field: "name", Change: airbyte.RecordChangeTruncated, Reason: airbyte.RecordChangeReasonDestinationFieldSizeLimitation},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expected, getAirbyteMeta(tt.recordMeta, tt.changes))
+		})
+	}
+}
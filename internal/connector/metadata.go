@@ -0,0 +1,186 @@
+package connector
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/propeldata/go-client/models"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+// MetadataColumn is an optional column recording when and how the connector loaded each record.
+type MetadataColumn string
+
+const (
+	// MetadataLoadedAt is the time the batch holding the record was published.
+	MetadataLoadedAt MetadataColumn = "loaded_at"
+	// MetadataSyncID identifies the sync that loaded the record.
+	MetadataSyncID MetadataColumn = "sync_id"
+	// MetadataStream is the name of the stream of the record.
+	MetadataStream MetadataColumn = "stream"
+	// MetadataNamespace is the namespace of the stream of the record, null when it has none.
+	MetadataNamespace MetadataColumn = "namespace"
+	// MetadataConnectionLabel is the connection_label of the configuration.
+	MetadataConnectionLabel MetadataColumn = "connection_label"
+)
+
+const (
+	airbyteLoadedAtColumn        = "_airbyte_loaded_at"
+	airbyteSyncIdColumn          = "_airbyte_sync_id"
+	airbyteStreamColumn          = "_airbyte_stream"
+	airbyteNamespaceColumn       = "_airbyte_namespace"
+	airbyteConnectionLabelColumn = "_airbyte_connection_label"
+
+	// loadedAtLayout formats load times with a fixed length, unlike time.RFC3339Nano.
+	loadedAtLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// metadataColumns lists the metadata columns in the order they are added to Data Sources. They are nullable, so they
+// can be added to existing Data Sources.
+var metadataColumns = []struct {
+	metadata MetadataColumn
+	column   *models.WebhookDataSourceColumnInput
+}{
+	{MetadataLoadedAt, &models.WebhookDataSourceColumnInput{Name: airbyteLoadedAtColumn, Type: models.TimestampPropelType, Nullable: true, JsonProperty: airbyteLoadedAtColumn}},
+	{MetadataSyncID, &models.WebhookDataSourceColumnInput{Name: airbyteSyncIdColumn, Type: models.StringPropelType, Nullable: true, JsonProperty: airbyteSyncIdColumn}},
+	{MetadataStream, &models.WebhookDataSourceColumnInput{Name: airbyteStreamColumn, Type: models.StringPropelType, Nullable: true, JsonProperty: airbyteStreamColumn}},
+	{MetadataNamespace, &models.WebhookDataSourceColumnInput{Name: airbyteNamespaceColumn, Type: models.StringPropelType, Nullable: true, JsonProperty: airbyteNamespaceColumn}},
+	{MetadataConnectionLabel, &models.WebhookDataSourceColumnInput{Name: airbyteConnectionLabelColumn, Type: models.StringPropelType, Nullable: true, JsonProperty: airbyteConnectionLabelColumn}},
+}
+
+// isAirbyteColumn reports whether the column is one of those the connector sets on every record, whether or not the
+// configuration chooses it, so that stream properties never claim their names.
+func isAirbyteColumn(columnName string) bool {
+	for _, column := range defaultAirbyteColumns {
+		if column.Name == columnName {
+			return true
+		}
+	}
+
+	for _, metadataColumn := range metadataColumns {
+		if metadataColumn.column.Name == columnName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// airbyteColumns returns the columns the connector sets on every record: the default ones, followed by the metadata
+// columns the configuration chooses.
+func (c Config) airbyteColumns() []*models.WebhookDataSourceColumnInput {
+	columns := make([]*models.WebhookDataSourceColumnInput, 0, len(defaultAirbyteColumns)+len(c.MetadataColumns))
+	columns = append(columns, defaultAirbyteColumns...)
+
+	for _, metadataColumn := range metadataColumns {
+		for _, metadata := range c.MetadataColumns {
+			if metadata == metadataColumn.metadata {
+				columns = append(columns, metadataColumn.column)
+				break
+			}
+		}
+	}
+
+	return columns
+}
+
+// syncMetadata sets the metadata columns of the records of a stream.
+type syncMetadata struct {
+	// values are the metadata that are the same for every record of the stream.
+	values   map[string]any
+	loadedAt bool
+	// stream and namespace are set from the stream of each record, as unioned streams share their Data Source.
+	stream    bool
+	namespace bool
+}
+
+// newSyncMetadata returns the metadata of the stream records, limited to the columns its Data Source has, or nil when
+// there are none.
+func newSyncMetadata(dstCfg Config, syncID string, coercer *recordCoercer) *syncMetadata {
+	sm := &syncMetadata{values: map[string]any{}}
+
+	for _, metadata := range dstCfg.MetadataColumns {
+		switch metadata {
+		case MetadataLoadedAt:
+			sm.loadedAt = coercer.hasColumn(airbyteLoadedAtColumn)
+		case MetadataSyncID:
+			sm.values[airbyteSyncIdColumn] = syncID
+		case MetadataStream:
+			sm.stream = coercer.hasColumn(airbyteStreamColumn)
+		case MetadataNamespace:
+			sm.namespace = coercer.hasColumn(airbyteNamespaceColumn)
+		case MetadataConnectionLabel:
+			sm.values[airbyteConnectionLabelColumn] = dstCfg.ConnectionLabel
+		}
+	}
+
+	for column := range sm.values {
+		if !coercer.hasColumn(column) {
+			delete(sm.values, column)
+		}
+	}
+
+	if len(sm.values) == 0 && !sm.loadedAt && !sm.stream && !sm.namespace {
+		return nil
+	}
+
+	return sm
+}
+
+// stamp sets the metadata columns of the record of the stream. The load time is only known once its batch is
+// published, so it is set to the current time in the meantime, which takes as many bytes in the batch.
+func (sm *syncMetadata) stamp(recordMap map[string]any, stream streamKey, now time.Time) {
+	if sm == nil {
+		return
+	}
+
+	for column, value := range sm.values {
+		recordMap[column] = value
+	}
+
+	if sm.stream {
+		recordMap[airbyteStreamColumn] = stream.name
+	}
+
+	if sm.namespace {
+		if stream.namespace != "" {
+			recordMap[airbyteNamespaceColumn] = stream.namespace
+		} else {
+			recordMap[airbyteNamespaceColumn] = nil
+		}
+	}
+
+	if sm.loadedAt {
+		recordMap[airbyteLoadedAtColumn] = now.UTC().Format(loadedAtLayout)
+	}
+}
+
+// stampLoadedAt sets the load time of the records of a batch about to be published.
+func (sm *syncMetadata) stampLoadedAt(events []map[string]any, loadedAt time.Time) {
+	if sm == nil || !sm.loadedAt {
+		return
+	}
+
+	value := loadedAt.UTC().Format(loadedAtLayout)
+	for _, event := range events {
+		event[airbyteLoadedAtColumn] = value
+	}
+}
+
+// newSyncID returns the sync ID of the configured catalog, or a random one when the platform sets none.
+func newSyncID(configuredCatalog airbyte.ConfiguredCatalog) string {
+	for _, configuredStream := range configuredCatalog.Streams {
+		if configuredStream.SyncID != 0 {
+			return strconv.FormatInt(configuredStream.SyncID, 10)
+		}
+	}
+
+	var b [16]byte
+	// crypto/rand never fails on the platforms the connector runs on
+	_, _ = rand.Read(b[:])
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,87 @@
+package connector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestConfig_AirbyteColumns(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(defaultAirbyteColumns, Config{}.airbyteColumns())
+
+	columns := Config{MetadataColumns: []MetadataColumn{MetadataStream, MetadataLoadedAt}}.airbyteColumns()
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+
+	a.Equal([]string{airbyteRawIdColumn, airbyteExtractedAtColumn, airbyteMetaColumn, airbyteLoadedAtColumn, airbyteStreamColumn}, names)
+}
+
+func TestSyncMetadata(t *testing.T) {
+	a := assert.New(t)
+
+	dstCfg := Config{
+		MetadataColumns: []MetadataColumn{MetadataLoadedAt, MetadataSyncID, MetadataStream, MetadataNamespace, MetadataConnectionLabel},
+		ConnectionLabel: "shop-production",
+	}
+	// the Data Source lacks the connection label column, which is then left out of the records
+	coercer := newRecordCoercer(&models.DataSource{
+		ConnectionSettings: models.ConnectionSettings{
+			WebhookConnectionSettings: models.WebhookConnectionSettings{
+				Columns: []models.WebhookColumn{
+					{Name: airbyteLoadedAtColumn, Type: models.TimestampPropelType, Nullable: true, JsonProperty: airbyteLoadedAtColumn},
+					{Name: airbyteSyncIdColumn, Type: models.StringPropelType, Nullable: true, JsonProperty: airbyteSyncIdColumn},
+					{Name: airbyteStreamColumn, Type: models.StringPropelType, Nullable: true, JsonProperty: airbyteStreamColumn},
+					{Name: airbyteNamespaceColumn, Type: models.StringPropelType, Nullable: true, JsonProperty: airbyteNamespaceColumn},
+				},
+			},
+		},
+	}, airbyte.ConfiguredStream{}, time.UTC)
+
+	metadata := newSyncMetadata(dstCfg, "42", coercer)
+	require.NotNil(t, metadata)
+
+	batchedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	record := map[string]any{"id": float64(1)}
+	metadata.stamp(record, streamKey{name: "orders"}, batchedAt)
+	a.Equal(map[string]any{
+		"id":                   float64(1),
+		airbyteLoadedAtColumn:  "2024-01-02T03:04:05.000000Z",
+		airbyteSyncIdColumn:    "42",
+		airbyteStreamColumn:    "orders",
+		airbyteNamespaceColumn: nil,
+	}, record)
+
+	metadata.stampLoadedAt([]map[string]any{record}, batchedAt.Add(1500*time.Millisecond))
+	a.Equal("2024-01-02T03:04:06.500000Z", record[airbyteLoadedAtColumn])
+
+	// records of unioned streams share the metadata of their Data Source, but get their own stream
+	unionRecord := map[string]any{"id": float64(2)}
+	metadata.stamp(unionRecord, streamKey{namespace: "shop", name: "refunds"}, batchedAt)
+	a.Equal("refunds", unionRecord[airbyteStreamColumn])
+	a.Equal("shop", unionRecord[airbyteNamespaceColumn])
+
+	a.Nil(newSyncMetadata(Config{}, "42", coercer))
+
+	var none *syncMetadata
+	none.stamp(record, streamKey{name: "orders"}, batchedAt)
+	none.stampLoadedAt([]map[string]any{record}, batchedAt)
+}
+
+func TestNewSyncID(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("42", newSyncID(airbyte.ConfiguredCatalog{Streams: []airbyte.ConfiguredStream{{SyncID: 42}}}))
+
+	syncID := newSyncID(airbyte.ConfiguredCatalog{})
+	a.Regexp(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, syncID)
+	a.NotEqual(syncID, newSyncID(airbyte.ConfiguredCatalog{}))
+}
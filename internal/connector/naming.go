@@ -0,0 +1,159 @@
+package connector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+const (
+	// defaultDataSourceNameTemplate names Data Sources after the namespace and the stream, as the connector always did.
+	defaultDataSourceNameTemplate = "{{prefix}}_{{namespace}}_{{stream}}"
+	// maxDataSourceNameLength is the length Data Source names are truncated to, hash suffix included.
+	maxDataSourceNameLength = 255
+	// nameHashLength is the number of hexadecimal digits of the hash suffix of truncated names.
+	nameHashLength = 8
+)
+
+var (
+	templatePlaceholderPattern = regexp.MustCompile(`\{\{([a-z]+)\}\}`)
+	invalidNameCharPattern     = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+	templatePlaceholders       = []string{"prefix", "namespace", "stream"}
+)
+
+// validateDataSourceNameTemplate checks that the template only holds known placeholders, including the stream one.
+func validateDataSourceNameTemplate(template string) error {
+	return validateNameTemplate("data_source_name_template", template, "stream", templatePlaceholders)
+}
+
+// validateNameTemplate checks that the template of the setting only holds the placeholders given, including the
+// required one.
+func validateNameTemplate(setting, template, required string, placeholders []string) error {
+	if !strings.Contains(template, "{{"+required+"}}") {
+		return fmt.Errorf("invalid %s %q, expected a {{%s}} placeholder", setting, template, required)
+	}
+
+	for _, match := range templatePlaceholderPattern.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(placeholders, match[1]) {
+			return fmt.Errorf("invalid %s placeholder %q, expected %s", setting, match[0], placeholderList(placeholders))
+		}
+	}
+
+	if remaining := templatePlaceholderPattern.ReplaceAllString(template, ""); strings.ContainsAny(remaining, "{}") {
+		return fmt.Errorf("invalid %s %q, placeholders must be written as {{name}}", setting, template)
+	}
+
+	return nil
+}
+
+// placeholderList formats placeholders for error messages, e.g. {{prefix}}, {{namespace}} or {{stream}}.
+func placeholderList(placeholders []string) string {
+	formatted := make([]string, 0, len(placeholders))
+	for _, placeholder := range placeholders {
+		formatted = append(formatted, "{{"+placeholder+"}}")
+	}
+
+	return strings.Join(formatted[:len(formatted)-1], ", ") + " or " + formatted[len(formatted)-1]
+}
+
+// dataSourceName returns the name of the Data Source the stream is written to.
+func (c Config) dataSourceName(namespace, streamName string) string {
+	template := c.DataSourceNameTemplate
+	if template == "" {
+		template = defaultDataSourceNameTemplate
+	}
+
+	return renderNameTemplate(template, map[string]string{"prefix": c.DataSourceNamePrefix, "namespace": namespace, "stream": streamName})
+}
+
+// renderNameTemplate replaces the placeholders of a Data Source name template. Placeholders without a value are left
+// out along with the separator that follows them, or the one that precedes the last placeholder, so that the default
+// template names Data Sources after their stream alone when there is no prefix nor namespace.
+func renderNameTemplate(template string, values map[string]string) string {
+	// literals holds the text around the placeholders, so literals[i] precedes placeholders[i]
+	literals := templatePlaceholderPattern.Split(template, -1)
+	placeholders := templatePlaceholderPattern.FindAllStringSubmatch(template, -1)
+
+	var builder strings.Builder
+	builder.WriteString(literals[0])
+	for i, placeholder := range placeholders {
+		value := values[placeholder[1]]
+		literal := literals[i+1]
+
+		if value == "" {
+			if i == len(placeholders)-1 && literal == "" {
+				name := strings.TrimSuffix(builder.String(), literals[i])
+				builder.Reset()
+				builder.WriteString(name)
+			}
+
+			continue
+		}
+
+		builder.WriteString(value)
+		builder.WriteString(literal)
+	}
+
+	return sanitizeDataSourceName(builder.String())
+}
+
+// sanitizeDataSourceName replaces every character other than ASCII letters, digits, underscores and hyphens by an
+// underscore. Names longer than the Propel limit are truncated and suffixed by a hash of the whole name, so that
+// names sharing a long prefix remain distinct.
+func sanitizeDataSourceName(name string) string {
+	sanitized := invalidNameCharPattern.ReplaceAllString(name, "_")
+	if len(sanitized) <= maxDataSourceNameLength {
+		return sanitized
+	}
+
+	hash := sha256.Sum256([]byte(name))
+	suffix := "_" + hex.EncodeToString(hash[:])[:nameHashLength]
+
+	return sanitized[:maxDataSourceNameLength-len(suffix)] + suffix
+}
+
+// checkDataSourceNameCollisions fails when several streams of the catalog, or several routing values of a stream, would
+// be written to the same Data Source. Routed streams are checked for every value of their allow list, as names are
+// sanitized, e.g. values "acme.eu" and "acme_eu" both name Data Source "orders_acme_eu".
+func checkDataSourceNameCollisions(dstCfg Config, planned []plannedStream) error {
+	streams := map[string]airbyte.Stream{}
+	for _, stream := range planned {
+		if stream.routing != nil {
+			continue
+		}
+
+		if other, ok := streams[stream.dataSourceUniqueName]; ok {
+			return fmt.Errorf("stream %q of namespace %q and stream %q of namespace %q would both be written to Data Source %q", other.Name, other.Namespace, stream.configuredStream.Stream.Name, stream.configuredStream.Stream.Namespace, stream.dataSourceUniqueName)
+		}
+
+		streams[stream.dataSourceUniqueName] = stream.configuredStream.Stream
+	}
+
+	for _, stream := range planned {
+		if stream.routing == nil {
+			continue
+		}
+
+		values := map[string]string{}
+		for _, value := range stream.routing.AllowList {
+			name := dstCfg.routedDataSourceName(*stream.routing, stream.configuredStream.Stream.Namespace, stream.configuredStream.Stream.Name, value)
+			if other, ok := values[name]; ok {
+				return fmt.Errorf("values %q and %q of routing field %q of stream %q would both be written to Data Source %q", other, value, stream.routing.Field, stream.streamName, name)
+			}
+
+			if other, ok := streams[name]; ok {
+				return fmt.Errorf("value %q of stream %q and stream %q of namespace %q would both be written to Data Source %q", value, stream.streamName, other.Name, other.Namespace, name)
+			}
+
+			values[name] = value
+			streams[name] = stream.configuredStream.Stream
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestConfig_DataSourceName(t *testing.T) {
+	tests := []struct {
+		name         string
+		template     string
+		prefix       string
+		namespace    string
+		streamName   string
+		expectedName string
+	}{
+		{name: "Default template", streamName: "orders", expectedName: "orders"},
+		{name: "Default template with namespace", namespace: "public", streamName: "orders", expectedName: "public_orders"},
+		{name: "Default template with prefix", prefix: "prod", streamName: "orders", expectedName: "prod_orders"},
+		{name: "Default template with prefix and namespace", prefix: "prod", namespace: "public", streamName: "orders", expectedName: "prod_public_orders"},
+		{name: "Custom template", template: "{{stream}}-{{prefix}}", prefix: "staging", streamName: "orders", expectedName: "orders-staging"},
+		{name: "Custom template without trailing value", template: "{{stream}}-{{namespace}}", streamName: "orders", expectedName: "orders"},
+		{name: "Template literals", template: "airbyte_{{stream}}", namespace: "public", streamName: "orders", expectedName: "airbyte_orders"},
+		{name: "Sanitized name", namespace: "sales.eu", streamName: "order items", expectedName: "sales_eu_order_items"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			cfg := Config{DataSourceNameTemplate: tt.template, DataSourceNamePrefix: tt.prefix}
+			assert.Equal(st, tt.expectedName, cfg.dataSourceName(tt.namespace, tt.streamName))
+		})
+	}
+}
+
+func TestSanitizeDataSourceName(t *testing.T) {
+	a := assert.New(t)
+
+	long := sanitizeDataSourceName(strings.Repeat("a", 300))
+	a.Len(long, maxDataSourceNameLength)
+	a.True(strings.HasPrefix(long, strings.Repeat("a", maxDataSourceNameLength-nameHashLength-1)+"_"))
+
+	other := sanitizeDataSourceName(strings.Repeat("a", 301))
+	a.Len(other, maxDataSourceNameLength)
+	a.NotEqual(long, other)
+}
+
+func TestValidateDataSourceNameTemplate(t *testing.T) {
+	tests := []struct {
+		name          string
+		template      string
+		expectedError string
+	}{
+		{name: "Valid template", template: "{{prefix}}_{{namespace}}_{{stream}}"},
+		{name: "Missing stream", template: "{{prefix}}_{{namespace}}", expectedError: `invalid data_source_name_template "{{prefix}}_{{namespace}}", expected a {{stream}} placeholder`},
+		{name: "Unknown placeholder", template: "{{env}}_{{stream}}", expectedError: `invalid data_source_name_template placeholder "{{env}}", expected {{prefix}}, {{namespace}} or {{stream}}`},
+		{name: "Malformed placeholder", template: "{stream}_{{stream}}", expectedError: `invalid data_source_name_template "{stream}_{{stream}}", placeholders must be written as {{name}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			err := validateDataSourceNameTemplate(tt.template)
+			if tt.expectedError == "" {
+				assert.NoError(st, err)
+				return
+			}
+
+			assert.EqualError(st, err, tt.expectedError)
+		})
+	}
+}
+
+func TestCheckDataSourceNameCollisions(t *testing.T) {
+	orders := plannedStream{
+		configuredStream:     airbyte.ConfiguredStream{Stream: airbyte.Stream{Name: "orders"}},
+		streamName:           "orders",
+		dataSourceUniqueName: "orders",
+	}
+	routed := func(allowList ...string) plannedStream {
+		return plannedStream{
+			configuredStream: airbyte.ConfiguredStream{Stream: airbyte.Stream{Name: "tenants"}},
+			streamName:       "tenants",
+			routing:          &RoutingConfig{Field: "tenant_id", DataSourceNameTemplate: "{{value}}", AllowList: allowList},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		planned       []plannedStream
+		expectedError string
+	}{
+		{name: "Distinct names", planned: []plannedStream{orders, routed("acme", "globex")}},
+		{name: "Sanitized values", planned: []plannedStream{routed("acme.eu", "acme_eu")}, expectedError: `values "acme.eu" and "acme_eu" of routing field "tenant_id" of stream "tenants" would both be written to Data Source "acme_eu"`},
+		{name: "Value named after a stream", planned: []plannedStream{orders, routed("orders")}, expectedError: `value "orders" of stream "tenants" and stream "orders" of namespace "" would both be written to Data Source "orders"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			err := checkDataSourceNameCollisions(Config{}, tt.planned)
+			if tt.expectedError == "" {
+				assert.NoError(st, err)
+				return
+			}
+
+			assert.EqualError(st, err, tt.expectedError)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package connector
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+const (
+	airbyteParentRawIdColumn  = "_airbyte_parent_raw_id"
+	airbyteParentColumnPrefix = "_airbyte_parent_"
+	airbyteArrayIndexColumn   = "_airbyte_array_index"
+	arrayValueColumn          = "value"
+	childStreamSeparator      = "__"
+)
+
+// arrayChild describes how the elements of an array property are fanned out into the records of a child stream.
+type arrayChild struct {
+	// property is the array property of the parent records.
+	property string
+	// streamName is the name of the child stream.
+	streamName string
+	// key identifies the target of the child stream.
+	key streamKey
+	// parentKeys are the primary key properties of the parent stream, copied into every child record.
+	parentKeys []string
+	// scalar is set when the elements are not objects, so they are written to the value column.
+	scalar bool
+}
+
+// childStreams returns the child streams of the array properties the stream normalizes, along with how to fan out
+// their elements. Child streams mirror the sync mode of their parent, and de-duplicate on the parent primary key
+// and the array index. Nothing marks the elements a parent array lost, so de-duplicating child streams keep those
+// past the end of a shrunk array, with the _airbyte_parent_raw_id of an earlier parent record.
+func childStreams(configuredStream airbyte.ConfiguredStream, streamConfig StreamConfig) ([]airbyte.ConfiguredStream, []*arrayChild, error) {
+	if len(streamConfig.NormalizeArrays) == 0 {
+		return nil, nil, nil
+	}
+
+	parentKeys := make([]string, 0, len(configuredStream.PrimaryKey))
+	for _, pk := range configuredStream.PrimaryKey {
+		if len(pk) != 1 {
+			return nil, nil, fmt.Errorf("unexpected primary key length %d", len(pk))
+		}
+
+		parentKeys = append(parentKeys, pk[0])
+	}
+
+	streams := make([]airbyte.ConfiguredStream, 0, len(streamConfig.NormalizeArrays))
+	children := make([]*arrayChild, 0, len(streamConfig.NormalizeArrays))
+
+	for _, property := range streamConfig.NormalizeArrays {
+		propertySpec, ok := configuredStream.Stream.JSONSchema.Properties[property]
+		if !ok || propertySpec.TypeSet == nil || !slices.Equal(removeNullType(propertySpec.TypeSet.Types), []airbyte.PropType{airbyte.Array}) {
+			return nil, nil, fmt.Errorf("property %q is not an array", property)
+		}
+
+		itemsSpec, err := decodeItemsSpec(propertySpec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("items of property %q: %w", property, err)
+		}
+
+		child := &arrayChild{
+			property:   property,
+			streamName: configuredStream.Stream.Name + childStreamSeparator + property,
+			parentKeys: parentKeys,
+			scalar:     !isFlattenableObject(itemsSpec),
+		}
+		child.key = streamKey{namespace: configuredStream.Stream.Namespace, name: child.streamName}
+
+		properties := map[string]airbyte.PropertySpec{
+			airbyteParentRawIdColumn: {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}}},
+			airbyteArrayIndexColumn:  {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Integer}}}},
+		}
+
+		primaryKey := make([][]string, 0, len(parentKeys)+1)
+		for _, parentKey := range parentKeys {
+			properties[airbyteParentColumnPrefix+parentKey] = configuredStream.Stream.JSONSchema.Properties[parentKey]
+			primaryKey = append(primaryKey, []string{airbyteParentColumnPrefix + parentKey})
+		}
+
+		if len(primaryKey) > 0 {
+			primaryKey = append(primaryKey, []string{airbyteArrayIndexColumn})
+		}
+
+		if child.scalar {
+			properties[arrayValueColumn] = itemsSpec
+		} else {
+			for elementProperty, elementSpec := range itemsSpec.Properties {
+				if _, ok := properties[elementProperty]; ok {
+					return nil, nil, fmt.Errorf("element property %q of array %q collides with a child stream column", elementProperty, property)
+				}
+
+				properties[elementProperty] = elementSpec
+			}
+		}
+
+		stream := configuredStream.Stream
+		stream.Name = child.streamName
+		stream.JSONSchema = airbyte.Properties{Properties: properties}
+
+		streams = append(streams, airbyte.ConfiguredStream{
+			Stream:              stream,
+			SyncMode:            configuredStream.SyncMode,
+			DestinationSyncMode: configuredStream.DestinationSyncMode,
+			PrimaryKey:          primaryKey,
+		})
+		children = append(children, child)
+	}
+
+	return streams, children, nil
+}
+
+// parentColumnMapping returns the column mapping of a stream normalizing arrays, which leaves the arrays out of its
+// Data Source, as their elements are written to child streams.
+func parentColumnMapping(mapping ColumnMapping, normalizeArrays []string) ColumnMapping {
+	for _, property := range normalizeArrays {
+		if !slices.Contains(mapping.Exclude, property) {
+			mapping.Exclude = append(slices.Clone(mapping.Exclude), property)
+		}
+	}
+
+	return mapping
+}
+
+// decodeItemsSpec returns the schema of the array elements, as resolved along with the stream schema when it was.
+// Arrays without items schema hold any value.
+func decodeItemsSpec(propertySpec airbyte.PropertySpec) (airbyte.PropertySpec, error) {
+	if propertySpec.ResolvedItems != nil {
+		return *propertySpec.ResolvedItems, nil
+	}
+
+	var itemsSpec airbyte.PropertySpec
+	if propertySpec.Items == nil {
+		return itemsSpec, nil
+	}
+
+	itemsJsonEncoded, err := json.Marshal(propertySpec.Items)
+	if err != nil {
+		return itemsSpec, err
+	}
+
+	if err := json.Unmarshal(itemsJsonEncoded, &itemsSpec); err != nil {
+		return itemsSpec, err
+	}
+
+	return itemsSpec, nil
+}
+
+// childRecords fans the elements of the parent record array out into child records.
+func (ac *arrayChild) childRecords(parentRecord map[string]any) []map[string]any {
+	elements, ok := parentRecord[ac.property].([]any)
+	if !ok {
+		return nil
+	}
+
+	records := make([]map[string]any, 0, len(elements))
+	for index, element := range elements {
+		record := map[string]any{}
+		if object, ok := element.(map[string]any); ok && !ac.scalar {
+			for key, value := range object {
+				record[key] = value
+			}
+		} else {
+			record[arrayValueColumn] = element
+		}
+
+		record[airbyteParentRawIdColumn] = parentRecord[airbyteRawIdColumn]
+		record[airbyteArrayIndexColumn] = int64(index)
+		for _, parentKey := range ac.parentKeys {
+			record[airbyteParentColumnPrefix+parentKey] = parentRecord[parentKey]
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
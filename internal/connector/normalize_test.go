@@ -0,0 +1,86 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestChildStreams(t *testing.T) {
+	c := require.New(t)
+
+	var configuredCatalog airbyte.ConfiguredCatalog
+	c.NoError(UnmarshalFromPath("./test_files/configured_catalog_arrays.json", &configuredCatalog))
+
+	streams, children, err := childStreams(configuredCatalog.Streams[0], StreamConfig{NormalizeArrays: []string{"line_items", "tags"}})
+	c.NoError(err)
+	c.Len(streams, 2)
+	c.Len(children, 2)
+
+	lineItems := streams[0]
+	c.Equal("orders__line_items", lineItems.Stream.Name)
+	c.Equal(airbyte.DestinationSyncModeAppendDedup, lineItems.DestinationSyncMode)
+	c.Equal([][]string{{"_airbyte_parent_id"}, {"_airbyte_array_index"}}, lineItems.PrimaryKey)
+	c.Contains(lineItems.Stream.JSONSchema.Properties, "sku")
+	c.Contains(lineItems.Stream.JSONSchema.Properties, "quantity")
+	c.Contains(lineItems.Stream.JSONSchema.Properties, "_airbyte_parent_raw_id")
+	c.False(children[0].scalar)
+
+	tags := streams[1]
+	c.Equal("orders__tags", tags.Stream.Name)
+	c.Contains(tags.Stream.JSONSchema.Properties, "value")
+	c.True(children[1].scalar)
+
+	_, _, err = childStreams(configuredCatalog.Streams[0], StreamConfig{NormalizeArrays: []string{"id"}})
+	c.EqualError(err, `property "id" is not an array`)
+}
+
+func TestArrayChild_ChildRecords(t *testing.T) {
+	tests := []struct {
+		name            string
+		child           arrayChild
+		parentRecord    map[string]any
+		expectedRecords []map[string]any
+	}{
+		{
+			name:         "Objects",
+			child:        arrayChild{property: "line_items", parentKeys: []string{"id"}},
+			parentRecord: map[string]any{"id": float64(1), "_airbyte_raw_id": "raw", "line_items": []any{map[string]any{"sku": "TACO-1"}, map[string]any{"sku": "BURRITO-1"}}},
+			expectedRecords: []map[string]any{
+				{"sku": "TACO-1", "_airbyte_parent_id": float64(1), "_airbyte_parent_raw_id": "raw", "_airbyte_array_index": int64(0)},
+				{"sku": "BURRITO-1", "_airbyte_parent_id": float64(1), "_airbyte_parent_raw_id": "raw", "_airbyte_array_index": int64(1)},
+			},
+		},
+		{
+			name:         "Scalars",
+			child:        arrayChild{property: "tags", scalar: true},
+			parentRecord: map[string]any{"_airbyte_raw_id": "raw", "tags": []any{"lunch"}},
+			expectedRecords: []map[string]any{
+				{"value": "lunch", "_airbyte_parent_raw_id": "raw", "_airbyte_array_index": int64(0)},
+			},
+		},
+		{
+			name:         "Missing array",
+			child:        arrayChild{property: "tags", scalar: true},
+			parentRecord: map[string]any{"_airbyte_raw_id": "raw", "tags": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expectedRecords, tt.child.childRecords(tt.parentRecord))
+		})
+	}
+}
+
+func TestParentColumnMapping(t *testing.T) {
+	mapping := ColumnMapping{Exclude: []string{"notes", "tags"}}
+
+	parentMapping := parentColumnMapping(mapping, []string{"tags", "line_items"})
+
+	assert.Equal(t, []string{"notes", "tags", "line_items"}, parentMapping.Exclude)
+	assert.Equal(t, []string{"notes", "tags"}, mapping.Exclude)
+}
@@ -0,0 +1,41 @@
+package connector
+
+import (
+	"fmt"
+
+	"github.com/propeldata/go-client/models"
+)
+
+// ownerUsernamePrefix marks the Data Sources created by the connector. Data Sources carry no user-defined metadata,
+// so ownership is recorded in the Basic auth username of their webhook, followed by the connection identifier.
+const ownerUsernamePrefix = "airbyte"
+
+// ownerUsername returns the Basic auth username of the Data Sources created for the connection.
+func ownerUsername(connectionID string) string {
+	return fmt.Sprintf("%s_%s", ownerUsernamePrefix, connectionID)
+}
+
+// isOwnedDataSource reports whether the Data Source was created by the connector for the given connection. Data Sources
+// created before ownership was recorded have the namespace of their stream as username, and are owned by the
+// connections writing to them.
+func isOwnedDataSource(dataSource *models.DataSource, connectionID, namespace string) bool {
+	basicAuth := dataSource.ConnectionSettings.WebhookConnectionSettings.BasicAuth
+
+	return basicAuth != nil && (basicAuth.Username == ownerUsername(connectionID) || basicAuth.Username == namespace)
+}
+
+// checkDestructiveOperation returns an error when the operation would delete data from a Data Source, or its
+// Data Pool, that the connection does not own, unless the configuration explicitly allows it.
+func (d *Destination) checkDestructiveOperation(dstCfg Config, dataSource *models.DataSource, namespace, operation string) error {
+	if isOwnedDataSource(dataSource, dstCfg.ConnectionID, namespace) {
+		return nil
+	}
+
+	if dstCfg.AllowUnownedDestructiveOperations {
+		return nil
+	}
+
+	d.traceConfigError(fmt.Sprintf("Refusing to %s Data Source %q: it was not created by this connection. Rename the stream or set allow_unowned_destructive_operations to proceed.", operation, dataSource.UniqueName))
+
+	return fmt.Errorf("refusing to %s Data Source %q not created by this connection", operation, dataSource.UniqueName)
+}
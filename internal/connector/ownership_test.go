@@ -0,0 +1,36 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsOwnedDataSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		basicAuth *models.HttpBasicAuth
+		namespace string
+		expected  bool
+	}{
+		{name: "Created by the connection", basicAuth: &models.HttpBasicAuth{Username: "airbyte_production"}, expected: true},
+		{name: "Created by another connection", basicAuth: &models.HttpBasicAuth{Username: "airbyte_staging"}, expected: false},
+		{name: "Created before ownership was recorded", basicAuth: &models.HttpBasicAuth{Username: "public"}, namespace: "public", expected: true},
+		{name: "Created before ownership was recorded, without namespace", basicAuth: &models.HttpBasicAuth{Username: ""}, expected: true},
+		{name: "Created outside the connector", basicAuth: &models.HttpBasicAuth{Username: "analytics"}, namespace: "public", expected: false},
+		{name: "Without Basic auth", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			dataSource := &models.DataSource{
+				ConnectionSettings: models.ConnectionSettings{
+					WebhookConnectionSettings: models.WebhookConnectionSettings{BasicAuth: tt.basicAuth},
+				},
+			}
+
+			assert.Equal(st, tt.expected, isOwnedDataSource(dataSource, "production", tt.namespace))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package connector
+
+import (
+	"slices"
+
+	"github.com/propeldata/go-client/models"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+const airbyteDataColumn = "_airbyte_data"
+
+// rawStream returns the stream as written in raw mode, where every record is stored whole in a single JSON column so
+// that schema changes never reach the Data Source. Raw streams have no primary key or cursor columns, so
+// de-duplicating streams are appended to instead.
+func rawStream(configuredStream airbyte.ConfiguredStream) airbyte.ConfiguredStream {
+	configuredStream.Stream.JSONSchema = airbyte.Properties{
+		Properties: map[string]airbyte.PropertySpec{
+			airbyteDataColumn: {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Object}}}},
+		},
+	}
+	configuredStream.PrimaryKey = nil
+	configuredStream.CursorField = nil
+
+	if configuredStream.DestinationSyncMode == airbyte.DestinationSyncModeAppendDedup {
+		configuredStream.DestinationSyncMode = airbyte.DestinationSyncModeAppend
+	}
+
+	return configuredStream
+}
+
+// rawAirbyteColumns returns the columns the connector sets on the records of raw streams: only those identifying each
+// record, as raw Data Sources hold nothing but the record data next to them. Without _airbyte_meta and the metadata
+// columns, records are written without them.
+func rawAirbyteColumns() []*models.WebhookDataSourceColumnInput {
+	return slices.DeleteFunc(slices.Clone(defaultAirbyteColumns), func(column *models.WebhookDataSourceColumnInput) bool {
+		return column.Name == airbyteMetaColumn
+	})
+}
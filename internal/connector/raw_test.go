@@ -0,0 +1,54 @@
+package connector
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/require"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestRawStream(t *testing.T) {
+	c := require.New(t)
+
+	var configuredCatalog airbyte.ConfiguredCatalog
+	c.NoError(UnmarshalFromPath("./test_files/configured_catalog_arrays.json", &configuredCatalog))
+
+	stream := rawStream(configuredCatalog.Streams[0])
+	c.Equal("orders", stream.Stream.Name)
+	c.Equal(airbyte.DestinationSyncModeAppend, stream.DestinationSyncMode)
+	c.Empty(stream.PrimaryKey)
+	c.Empty(stream.CursorField)
+	c.Len(stream.Stream.JSONSchema.Properties, 1)
+
+	propelType, err := ConvertAirbyteTypeToPropelType(stream.Stream.JSONSchema.Properties[airbyteDataColumn].PropertyType)
+	c.NoError(err)
+	c.Equal(models.JsonPropelType, propelType)
+
+	c.Len(configuredCatalog.Streams[0].PrimaryKey, 1, "the configured stream is left untouched")
+}
+
+func TestDestination_BuildDataSourceOpts_Raw(t *testing.T) {
+	c := require.New(t)
+
+	var configuredCatalog airbyte.ConfiguredCatalog
+	c.NoError(UnmarshalFromPath("./test_files/configured_catalog_arrays.json", &configuredCatalog))
+
+	dstCfg := Config{
+		MetadataColumns: []MetadataColumn{MetadataLoadedAt},
+		Streams:         map[string]StreamConfig{"orders": {Raw: true}},
+	}
+	d := NewMockDestination(airbyte.NewLogger(bytes.NewBufferString("")))
+
+	opts, err := d.buildDataSourceOpts(dstCfg, rawStream(configuredCatalog.Streams[0]), "orders", nil)
+	c.NoError(err)
+
+	names := make([]string, 0, len(opts.Columns))
+	for _, column := range opts.Columns {
+		names = append(names, column.Name)
+	}
+
+	c.Equal([]string{airbyteDataColumn, airbyteRawIdColumn, airbyteExtractedAtColumn}, names)
+}
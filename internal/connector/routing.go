@@ -0,0 +1,176 @@
+package connector
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+const (
+	// defaultRoutedDataSourceNameTemplate names routed Data Sources after their stream and routing value.
+	defaultRoutedDataSourceNameTemplate = "{{prefix}}_{{namespace}}_{{stream}}_{{value}}"
+	// defaultMaxRoutedDataSources caps the number of Data Sources a stream is routed to.
+	defaultMaxRoutedDataSources = 100
+)
+
+var routedTemplatePlaceholders = []string{"prefix", "namespace", "stream", "value"}
+
+// RoutingConfig splits the records of a stream across Data Sources by the value of one of its properties, such as a
+// tenant ID.
+type RoutingConfig struct {
+	Field                  string   `json:"field"`
+	DataSourceNameTemplate string   `json:"data_source_name_template,omitempty"`
+	AllowList              []string `json:"allow_list,omitempty"`
+	MaxDataSources         int      `json:"max_data_sources,omitempty"`
+}
+
+// validate checks the routing of the stream.
+func (rc RoutingConfig) validate() error {
+	if rc.Field == "" {
+		return fmt.Errorf("routing needs a field")
+	}
+
+	// no API lists the Data Sources of earlier syncs, so only an allow list bounds those a stream is routed to
+	if len(rc.AllowList) == 0 {
+		return fmt.Errorf("routing needs an allow_list, as the Data Sources of earlier syncs can't be counted against max_data_sources")
+	}
+
+	if rc.DataSourceNameTemplate != "" {
+		if err := validateNameTemplate("routing data_source_name_template", rc.DataSourceNameTemplate, "value", routedTemplatePlaceholders); err != nil {
+			return err
+		}
+	}
+
+	if rc.MaxDataSources < 0 {
+		return fmt.Errorf("invalid routing max_data_sources %d, expected 0 or more", rc.MaxDataSources)
+	}
+
+	if len(rc.AllowList) > rc.maxDataSources() {
+		return fmt.Errorf("routing allow_list has %d values, more than the %d of max_data_sources", len(rc.AllowList), rc.maxDataSources())
+	}
+
+	return nil
+}
+
+func (rc RoutingConfig) maxDataSources() int {
+	if rc.MaxDataSources == 0 {
+		return defaultMaxRoutedDataSources
+	}
+
+	return rc.MaxDataSources
+}
+
+// routedDataSourceName returns the name of the Data Source the records of the stream holding the routing value are
+// written to.
+func (c Config) routedDataSourceName(routing RoutingConfig, namespace, streamName, value string) string {
+	template := routing.DataSourceNameTemplate
+	if template == "" {
+		template = defaultRoutedDataSourceNameTemplate
+	}
+
+	return renderNameTemplate(template, map[string]string{"prefix": c.DataSourceNamePrefix, "namespace": namespace, "stream": streamName, "value": value})
+}
+
+// router holds the targets a stream is routed to, keyed by routing value, and sets them up as new values appear.
+type router struct {
+	routing RoutingConfig
+	targets map[string]*streamTarget
+	// setUp returns the target of a routing value, setting up its Data Source.
+	setUp func(value string) (*streamTarget, error)
+	// unrouted counts the records without a routing value, and denied those whose value the allow list lacks.
+	unrouted int
+	denied   int
+}
+
+func newRouter(routing RoutingConfig, setUp func(value string) (*streamTarget, error)) *router {
+	return &router{routing: routing, targets: map[string]*streamTarget{}, setUp: setUp}
+}
+
+// route returns the target of the record data. It returns false when the record can't be routed, as it has no routing
+// value or one the allow list lacks, and fails when a new value would exceed the number of Data Sources allowed.
+func (r *router) route(data map[string]any) (*streamTarget, bool, error) {
+	value, ok := routingValue(data[r.routing.Field])
+	if !ok {
+		r.unrouted++
+		return nil, false, nil
+	}
+
+	if len(r.routing.AllowList) > 0 && !slices.Contains(r.routing.AllowList, value) {
+		r.denied++
+		return nil, false, nil
+	}
+
+	target, err := r.target(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return target, true, nil
+}
+
+// target returns the target of the routing value, setting it up when it is new.
+func (r *router) target(value string) (*streamTarget, error) {
+	if target, ok := r.targets[value]; ok {
+		return target, nil
+	}
+
+	if len(r.targets) >= r.routing.maxDataSources() {
+		return nil, fmt.Errorf("value %q of routing field %q exceeds the %d Data Sources of max_data_sources", value, r.routing.Field, r.routing.maxDataSources())
+	}
+
+	target, err := r.setUp(value)
+	if err != nil {
+		return nil, err
+	}
+
+	r.targets[value] = target
+
+	return target, nil
+}
+
+// routedTargets returns the targets set up so far, ordered by routing value.
+func (r *router) routedTargets() []*streamTarget {
+	values := make([]string, 0, len(r.targets))
+	for value := range r.targets {
+		values = append(values, value)
+	}
+	slices.Sort(values)
+
+	targets := make([]*streamTarget, 0, len(values))
+	for _, value := range values {
+		targets = append(targets, r.targets[value])
+	}
+
+	return targets
+}
+
+// report summarizes the records left out of the Data Sources.
+func (r *router) report() []string {
+	lines := make([]string, 0, 2)
+	if r.unrouted > 0 {
+		lines = append(lines, fmt.Sprintf("%d records without a value of routing field %q were left out", r.unrouted, r.routing.Field))
+	}
+
+	if r.denied > 0 {
+		lines = append(lines, fmt.Sprintf("%d records of values missing from the routing allow_list were left out", r.denied))
+	}
+
+	return lines
+}
+
+// routingValue returns the routing value of a property, which must be a string, a number or a boolean.
+func routingValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case json.Number:
+		return v.String(), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+
+	return "", false
+}
@@ -0,0 +1,115 @@
+package connector
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_Route(t *testing.T) {
+	c := require.New(t)
+
+	setUps := make([]string, 0)
+	r := newRouter(RoutingConfig{Field: "tenant_id", AllowList: []string{"1", "2", "acme"}, MaxDataSources: 2}, func(value string) (*streamTarget, error) {
+		setUps = append(setUps, value)
+		return &streamTarget{dataSource: &models.DataSource{UniqueName: "orders_" + value}}, nil
+	})
+
+	target, ok, err := r.route(map[string]any{"tenant_id": json.Number("1")})
+	c.NoError(err)
+	c.True(ok)
+	c.Equal("orders_1", target.dataSource.UniqueName)
+
+	again, ok, err := r.route(map[string]any{"tenant_id": json.Number("1")})
+	c.NoError(err)
+	c.True(ok)
+	c.True(target == again)
+
+	_, ok, err = r.route(map[string]any{"tenant_id": nil})
+	c.NoError(err)
+	c.False(ok)
+
+	_, ok, err = r.route(map[string]any{"tenant_id": "globex"})
+	c.NoError(err)
+	c.False(ok)
+
+	_, ok, err = r.route(map[string]any{"tenant_id": "acme"})
+	c.NoError(err)
+	c.True(ok)
+
+	_, _, err = r.route(map[string]any{"tenant_id": json.Number("2")})
+	c.EqualError(err, `value "2" of routing field "tenant_id" exceeds the 2 Data Sources of max_data_sources`)
+
+	c.Equal([]string{"1", "acme"}, setUps)
+	c.Len(r.routedTargets(), 2)
+	c.Equal("orders_acme", r.routedTargets()[1].dataSource.UniqueName)
+	c.Equal([]string{
+		`1 records without a value of routing field "tenant_id" were left out`,
+		"1 records of values missing from the routing allow_list were left out",
+	}, r.report())
+}
+
+func TestRoutingValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         any
+		expectedValue string
+		expectedOk    bool
+	}{
+		{name: "String", value: "acme", expectedValue: "acme", expectedOk: true},
+		{name: "Empty string", value: ""},
+		{name: "Number", value: json.Number("42"), expectedValue: "42", expectedOk: true},
+		{name: "Float", value: float64(4.5), expectedValue: "4.5", expectedOk: true},
+		{name: "Boolean", value: true, expectedValue: "true", expectedOk: true},
+		{name: "Null", value: nil},
+		{name: "Object", value: map[string]any{"id": "acme"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			value, ok := routingValue(tt.value)
+			assert.Equal(st, tt.expectedValue, value)
+			assert.Equal(st, tt.expectedOk, ok)
+		})
+	}
+}
+
+func TestRoutingConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		routing       RoutingConfig
+		expectedError string
+	}{
+		{name: "Valid", routing: RoutingConfig{Field: "tenant_id", DataSourceNameTemplate: "{{value}}_{{stream}}", AllowList: []string{"acme"}}},
+		{name: "Missing field", routing: RoutingConfig{}, expectedError: "routing needs a field"},
+		{name: "Missing allow list", routing: RoutingConfig{Field: "tenant_id"}, expectedError: "routing needs an allow_list, as the Data Sources of earlier syncs can't be counted against max_data_sources"},
+		{name: "Template without value", routing: RoutingConfig{Field: "tenant_id", DataSourceNameTemplate: "{{stream}}", AllowList: []string{"acme"}}, expectedError: `invalid routing data_source_name_template "{{stream}}", expected a {{value}} placeholder`},
+		{name: "Unknown placeholder", routing: RoutingConfig{Field: "tenant_id", DataSourceNameTemplate: "{{env}}_{{value}}", AllowList: []string{"acme"}}, expectedError: `invalid routing data_source_name_template placeholder "{{env}}", expected {{prefix}}, {{namespace}}, {{stream}} or {{value}}`},
+		{name: "Negative cap", routing: RoutingConfig{Field: "tenant_id", AllowList: []string{"acme"}, MaxDataSources: -1}, expectedError: "invalid routing max_data_sources -1, expected 0 or more"},
+		{name: "Allow list over cap", routing: RoutingConfig{Field: "tenant_id", AllowList: []string{"a", "b"}, MaxDataSources: 1}, expectedError: "routing allow_list has 2 values, more than the 1 of max_data_sources"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			err := tt.routing.validate()
+			if tt.expectedError == "" {
+				assert.NoError(st, err)
+				return
+			}
+
+			assert.EqualError(st, err, tt.expectedError)
+		})
+	}
+}
+
+func TestConfig_RoutedDataSourceName(t *testing.T) {
+	a := assert.New(t)
+
+	cfg := Config{DataSourceNamePrefix: "prod"}
+	a.Equal("prod_public_orders_acme", cfg.routedDataSourceName(RoutingConfig{Field: "tenant_id"}, "public", "orders", "acme"))
+	a.Equal("prod_orders_acme_corp", cfg.routedDataSourceName(RoutingConfig{Field: "tenant_id"}, "", "orders", "acme corp"))
+	a.Equal("tenant-42", cfg.routedDataSourceName(RoutingConfig{Field: "tenant_id", DataSourceNameTemplate: "tenant-{{value}}"}, "", "orders", "42"))
+}
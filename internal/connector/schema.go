@@ -0,0 +1,196 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+// resolveCatalogSchemas normalizes the JSON schema of every configured stream in place.
+func resolveCatalogSchemas(configuredCatalog *airbyte.ConfiguredCatalog) error {
+	for i, configuredStream := range configuredCatalog.Streams {
+		schema, err := resolveSchema(configuredStream.Stream.JSONSchema)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the schema of stream %q: %w", configuredStream.Stream.Name, err)
+		}
+
+		configuredCatalog.Streams[i].Stream.JSONSchema = schema
+	}
+
+	return nil
+}
+
+// schemaResolver replaces local references with their definitions and merges anyOf and oneOf branches, so that
+// every property is described by a single PropertySpec.
+type schemaResolver struct {
+	// definitions are keyed by their local reference, e.g. "#/definitions/address".
+	definitions map[string]airbyte.PropertySpec
+}
+
+func resolveSchema(schema airbyte.Properties) (airbyte.Properties, error) {
+	resolver := schemaResolver{definitions: make(map[string]airbyte.PropertySpec, len(schema.Definitions)+len(schema.Defs))}
+	for name, definition := range schema.Definitions {
+		resolver.definitions["#/definitions/"+name] = definition
+	}
+
+	for name, definition := range schema.Defs {
+		resolver.definitions["#/$defs/"+name] = definition
+	}
+
+	properties, err := resolver.resolveProperties(schema.Properties, nil)
+	if err != nil {
+		return airbyte.Properties{}, err
+	}
+
+	return airbyte.Properties{Properties: properties, Required: schema.Required}, nil
+}
+
+func (sr schemaResolver) resolveProperties(properties map[string]airbyte.PropertySpec, visiting []string) (map[string]airbyte.PropertySpec, error) {
+	if properties == nil {
+		return nil, nil
+	}
+
+	resolvedProperties := make(map[string]airbyte.PropertySpec, len(properties))
+	for propertyName, propertySpec := range properties {
+		resolved, err := sr.resolve(propertySpec, visiting)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", propertyName, err)
+		}
+
+		resolvedProperties[propertyName] = resolved
+	}
+
+	return resolvedProperties, nil
+}
+
+// resolve returns the property spec with its reference and union branches resolved. visiting holds the references
+// being resolved, to detect recursive definitions.
+func (sr schemaResolver) resolve(propertySpec airbyte.PropertySpec, visiting []string) (airbyte.PropertySpec, error) {
+	if propertySpec.Ref != "" {
+		definition, ok := sr.definitions[propertySpec.Ref]
+		if !ok {
+			return airbyte.PropertySpec{}, fmt.Errorf("reference %q is unknown or not local", propertySpec.Ref)
+		}
+
+		if slices.Contains(visiting, propertySpec.Ref) {
+			// recursive definitions can't be expanded into columns, their values are kept as JSON objects
+			return airbyte.PropertySpec{
+				Title:        propertySpec.Title,
+				Description:  propertySpec.Description,
+				PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Object}}},
+			}, nil
+		}
+
+		resolved, err := sr.resolve(definition, append(slices.Clone(visiting), propertySpec.Ref))
+		if err != nil {
+			return airbyte.PropertySpec{}, err
+		}
+
+		if propertySpec.Title != "" {
+			resolved.Title = propertySpec.Title
+		}
+
+		if propertySpec.Description != "" {
+			resolved.Description = propertySpec.Description
+		}
+
+		return resolved, nil
+	}
+
+	properties, err := sr.resolveProperties(propertySpec.Properties, visiting)
+	if err != nil {
+		return airbyte.PropertySpec{}, err
+	}
+
+	propertySpec.Properties = properties
+
+	if propertySpec.Items != nil {
+		itemsSpec, err := decodeItemsSpec(propertySpec)
+		if err != nil {
+			return airbyte.PropertySpec{}, fmt.Errorf("items: %w", err)
+		}
+
+		resolvedItems, err := sr.resolve(itemsSpec, visiting)
+		if err != nil {
+			return airbyte.PropertySpec{}, fmt.Errorf("items: %w", err)
+		}
+
+		propertySpec.ResolvedItems = &resolvedItems
+	}
+
+	branches := append(slices.Clone(propertySpec.AnyOf), propertySpec.OneOf...)
+	if len(branches) == 0 {
+		return propertySpec, nil
+	}
+
+	resolvedBranches := make([]airbyte.PropertySpec, 0, len(branches))
+	for _, branch := range branches {
+		resolved, err := sr.resolve(branch, visiting)
+		if err != nil {
+			return airbyte.PropertySpec{}, err
+		}
+
+		resolvedBranches = append(resolvedBranches, resolved)
+
+		for propertyName, nestedSpec := range resolved.Properties {
+			if propertySpec.Properties == nil {
+				propertySpec.Properties = map[string]airbyte.PropertySpec{}
+			}
+
+			if _, ok := propertySpec.Properties[propertyName]; !ok {
+				propertySpec.Properties[propertyName] = nestedSpec
+			}
+		}
+	}
+
+	propertySpec.AnyOf, propertySpec.OneOf = nil, nil
+	if propertySpec.TypeSet == nil {
+		propertySpec.PropertyType = mergeBranchTypes(resolvedBranches)
+	}
+
+	return propertySpec, nil
+}
+
+// mergeBranchTypes returns the type of a union: every type of its branches, plus the format and Airbyte type all
+// of its non-null branches share. A branch without type accepts any value, and so does the union.
+func mergeBranchTypes(branches []airbyte.PropertySpec) airbyte.PropertyType {
+	merged := airbyte.PropertyType{TypeSet: &airbyte.PropTypes{}}
+
+	for _, branch := range branches {
+		if branch.TypeSet == nil {
+			return airbyte.PropertyType{}
+		}
+
+		if len(branch.Union) > 0 {
+			merged.Union = append(merged.Union, branch.Union...)
+		} else {
+			merged.Union = append(merged.Union, branch.PropertyType)
+		}
+
+		for _, propType := range branch.TypeSet.Types {
+			if !slices.Contains(merged.TypeSet.Types, propType) {
+				merged.TypeSet.Types = append(merged.TypeSet.Types, propType)
+			}
+		}
+	}
+
+	first := true
+	for _, branchType := range merged.Union {
+		if len(removeNullType(branchType.TypeSet.Types)) == 0 {
+			continue
+		}
+
+		if first {
+			merged.Format, merged.AirbyteType = branchType.Format, branchType.AirbyteType
+			first = false
+			continue
+		}
+
+		if merged.Format != branchType.Format || merged.AirbyteType != branchType.AirbyteType {
+			merged.Format, merged.AirbyteType = "", ""
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,216 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/propeldata/go-client"
+	"github.com/propeldata/go-client/models"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+var (
+	addColumnJobTimeout = 20 * time.Minute
+	addColumnJobDelay   = 3 * time.Second
+)
+
+// schemaChanges describes how a stream schema differs from the columns of its existing Data Source.
+type schemaChanges struct {
+	// newColumns are stream properties without a column in the Data Source.
+	newColumns []*models.WebhookDataSourceColumnInput
+	// widened are type changes the existing columns can still hold.
+	widened []string
+	// incompatible are type changes the existing columns can't hold.
+	incompatible []string
+}
+
+func (sc schemaChanges) isEmpty() bool {
+	return len(sc.newColumns) == 0 && len(sc.widened) == 0 && len(sc.incompatible) == 0
+}
+
+// diffSchema compares the columns the stream would create its Data Source with against the existing columns, matched
+// by the property they are filled from. Add Column Jobs only take a name, so columns whose name differs from their
+// property can't be added.
+func diffSchema(dataSource *models.DataSource, createDataSourceOpts client.CreateDataSourceOpts) schemaChanges {
+	existingColumns := columnsByProperty(dataSource)
+
+	changes := schemaChanges{
+		newColumns:   make([]*models.WebhookDataSourceColumnInput, 0),
+		widened:      make([]string, 0),
+		incompatible: make([]string, 0),
+	}
+
+	for _, column := range createDataSourceOpts.Columns {
+		existing, ok := existingColumns[column.JsonProperty]
+		if !ok {
+			// Data Sources created before change tracking existed lack _airbyte_meta, which is only set when they have it
+			if column.Name == airbyteMetaColumn {
+				continue
+			}
+
+			if column.Name != column.JsonProperty {
+				changes.incompatible = append(changes.incompatible, fmt.Sprintf("property %q needs column %q, which can't be added as added columns are filled from the property of their name", column.JsonProperty, column.Name))
+				continue
+			}
+
+			changes.newColumns = append(changes.newColumns, column)
+			continue
+		}
+
+		if existing.Type == column.Type {
+			continue
+		}
+
+		change := fmt.Sprintf("column %q type changed from %s to %s", column.Name, existing.Type.String(), column.Type.String())
+		if canHoldPropelType(existing.Type, column.Type) {
+			changes.widened = append(changes.widened, change)
+		} else {
+			changes.incompatible = append(changes.incompatible, change)
+		}
+	}
+
+	return changes
+}
+
+// keepBigNumberColumns keeps the type of the INT64 and DOUBLE columns existing Data Sources hold big integers and big
+// numbers in, as they were created before these became STRING columns. It returns the columns kept.
+func keepBigNumberColumns(dataSource *models.DataSource, configuredStream airbyte.ConfiguredStream, createDataSourceOpts client.CreateDataSourceOpts) []string {
+	existingColumns := columnsByProperty(dataSource)
+
+	kept := make([]string, 0)
+	for _, column := range createDataSourceOpts.Columns {
+		propertySpec, ok := configuredStream.Stream.JSONSchema.Properties[column.JsonProperty]
+		if !ok || !isBigNumber(propertySpec.PropertyType) || column.Type != models.StringPropelType {
+			continue
+		}
+
+		existing, ok := existingColumns[column.JsonProperty]
+		if ok && (existing.Type == models.Int64PropelType || existing.Type == models.DoublePropelType) {
+			column.Type = existing.Type
+			kept = append(kept, column.Name)
+		}
+	}
+
+	return kept
+}
+
+// evolveSchema applies the stream schema changes to its existing Data Source as the schema change policy dictates.
+// New columns are added to the Data Source in place, so records can be written to them straight away.
+func (d *Destination) evolveSchema(ctx context.Context, dstCfg Config, dataSource *models.DataSource, createDataSourceOpts client.CreateDataSourceOpts, apiClient PropelApiClient) error {
+	changes := diffSchema(dataSource, createDataSourceOpts)
+	if changes.isEmpty() {
+		return nil
+	}
+
+	for _, change := range changes.widened {
+		d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Data Source %q %s, the existing column can hold the new values", dataSource.UniqueName, change))
+	}
+
+	newColumnNames := make([]string, 0, len(changes.newColumns))
+	for _, column := range changes.newColumns {
+		newColumnNames = append(newColumnNames, column.Name)
+	}
+
+	switch dstCfg.SchemaChangePolicy {
+	case SchemaChangePolicyIgnore:
+		if len(newColumnNames) > 0 {
+			d.logger.Log(airbyte.LogLevelWarn, fmt.Sprintf("Data Source %q is missing columns %v, their values will be dropped", dataSource.UniqueName, newColumnNames))
+		}
+
+		for _, change := range changes.incompatible {
+			d.logger.Log(airbyte.LogLevelWarn, fmt.Sprintf("Data Source %q %s and can't hold the new values", dataSource.UniqueName, change))
+		}
+
+		return nil
+	case SchemaChangePolicyFail:
+		if len(newColumnNames) > 0 || len(changes.incompatible) > 0 {
+			report := changes.incompatible
+			if len(newColumnNames) > 0 {
+				report = append(report, fmt.Sprintf("new columns %v", newColumnNames))
+			}
+
+			return d.schemaChangeError(dataSource, report)
+		}
+
+		return nil
+	}
+
+	if len(changes.incompatible) > 0 {
+		return d.schemaChangeError(dataSource, changes.incompatible)
+	}
+
+	if len(changes.newColumns) == 0 {
+		return nil
+	}
+
+	dataPool, err := apiClient.FetchDataPool(ctx, dataSource.UniqueName)
+	if err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Data Pool %q failed: %v", dataSource.UniqueName, err))
+		return fmt.Errorf("failed to get Data Pool: %w", err)
+	}
+
+	for _, column := range changes.newColumns {
+		if err := d.addColumn(ctx, apiClient, dataPool, column); err != nil {
+			return err
+		}
+
+		dataSource.ConnectionSettings.WebhookConnectionSettings.Columns = append(dataSource.ConnectionSettings.WebhookConnectionSettings.Columns, models.WebhookColumn{
+			Name:         column.Name,
+			Type:         column.Type,
+			Nullable:     true,
+			JsonProperty: column.JsonProperty,
+		})
+	}
+
+	return nil
+}
+
+func (d *Destination) schemaChangeError(dataSource *models.DataSource, changes []string) error {
+	report := strings.Join(changes, "; ")
+	message := fmt.Sprintf("Schema of Data Source %q changed: %s. Update schema_change_policy or recreate the Data Source.", dataSource.UniqueName, report)
+
+	d.traceConfigError(message)
+
+	return fmt.Errorf("schema of Data Source %q changed: %s", dataSource.UniqueName, report)
+}
+
+// addColumn adds a nullable column to the Data Pool and waits for the job to finish.
+func (d *Destination) addColumn(ctx context.Context, apiClient PropelApiClient, dataPool *models.DataPool, column *models.WebhookDataSourceColumnInput) error {
+	addColumnJob, err := apiClient.CreateAddColumnJob(ctx, dataPool.ID, column.Name, column.Type.String())
+	if err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Add Column Job creation failed: %v", err))
+		return fmt.Errorf("failed to create Add Column Job for column %q in Data Pool %q: %w", column.Name, dataPool.ID, err)
+	}
+
+	addColumnJobUpdated, err := client.WaitForState(client.StateChangeOps[models.Job]{
+		Pending: []string{"CREATED", "IN_PROGRESS"},
+		Target:  []string{"SUCCEEDED", "FAILED"},
+		Refresh: func() (*models.Job, string, error) {
+			resp, err := apiClient.FetchAddColumnJob(ctx, addColumnJob.ID)
+			if err != nil {
+				d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Fetch Add Column Job %q failed: %v", addColumnJob.ID, err))
+				return nil, "", fmt.Errorf("failed to get Add Column Job: %w", err)
+			}
+
+			return resp, resp.Status, nil
+		},
+		Timeout: addColumnJobTimeout,
+		Delay:   addColumnJobDelay,
+	})
+	if err != nil {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Add Column Job %q state transition failed: %v", addColumnJob.ID, err))
+		return fmt.Errorf("state transition for add column job %q failed: %w", addColumnJob.ID, err)
+	}
+
+	if addColumnJobUpdated.Status == "FAILED" {
+		d.logger.Log(airbyte.LogLevelError, fmt.Sprintf("Add Column Job %q failed: %s", addColumnJob.ID, addColumnJobUpdated.Error.Message))
+		return fmt.Errorf("add column job %q failed: %s", addColumnJob.ID, addColumnJobUpdated.Error.Message)
+	}
+
+	d.logger.Log(airbyte.LogLevelInfo, fmt.Sprintf("Column %q of type %s added to Data Pool %q", column.Name, column.Type.String(), dataPool.ID))
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/propeldata/go-client"
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestDiffSchema(t *testing.T) {
+	dataSource := &models.DataSource{
+		UniqueName: "orders",
+		ConnectionSettings: models.ConnectionSettings{
+			WebhookConnectionSettings: models.WebhookConnectionSettings{
+				Columns: []models.WebhookColumn{
+					{Name: "id", Type: models.Int64PropelType},
+					{Name: "amount", Type: models.Int64PropelType},
+					{Name: "quantity", Type: models.Int64PropelType},
+					{Name: "shipped_on", Type: models.DatePropelType},
+					{Name: "total", Type: models.DoublePropelType},
+				},
+			},
+		},
+	}
+
+	changes := diffSchema(dataSource, client.CreateDataSourceOpts{
+		Columns: []*models.WebhookDataSourceColumnInput{
+			{Name: "id", Type: models.Int64PropelType, JsonProperty: "id"},
+			{Name: "amount", Type: models.DoublePropelType, JsonProperty: "amount"},
+			{Name: "quantity", Type: models.Int32PropelType, JsonProperty: "quantity"},
+			{Name: "shipped_on", Type: models.TimestampPropelType, JsonProperty: "shipped_on"},
+			{Name: "total", Type: models.Int64PropelType, JsonProperty: "total"},
+			{Name: "note", Type: models.StringPropelType, Nullable: true, JsonProperty: "note"},
+			{Name: "gift_note", Type: models.StringPropelType, Nullable: true, JsonProperty: "gift note"},
+			{Name: airbyteMetaColumn, Type: models.JsonPropelType, Nullable: true, JsonProperty: airbyteMetaColumn},
+		},
+	})
+
+	a := assert.New(t)
+	a.Len(changes.newColumns, 1)
+	a.Equal("note", changes.newColumns[0].Name)
+	a.Equal([]string{`column "quantity" type changed from INT64 to INT32`}, changes.widened)
+	a.Equal([]string{
+		`column "amount" type changed from INT64 to DOUBLE`,
+		`column "shipped_on" type changed from DATE to TIMESTAMP`,
+		`column "total" type changed from DOUBLE to INT64`,
+		`property "gift note" needs column "gift_note", which can't be added as added columns are filled from the property of their name`,
+	}, changes.incompatible)
+}
+
+func TestKeepBigNumberColumns(t *testing.T) {
+	bigIntegerType := airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Integer}}, AirbyteType: airbyte.BigInteger}
+	bigNumberType := airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Number}}, AirbyteType: airbyte.BigNumber}
+
+	dataSource := &models.DataSource{
+		ConnectionSettings: models.ConnectionSettings{
+			WebhookConnectionSettings: models.WebhookConnectionSettings{
+				Columns: []models.WebhookColumn{
+					{Name: "balance", Type: models.DoublePropelType, JsonProperty: "balance"},
+					{Name: "account_number", Type: models.Int64PropelType, JsonProperty: "account_number"},
+					{Name: "reference", Type: models.StringPropelType, JsonProperty: "reference"},
+				},
+			},
+		},
+	}
+
+	configuredStream := airbyte.ConfiguredStream{Stream: airbyte.Stream{JSONSchema: airbyte.Properties{Properties: map[string]airbyte.PropertySpec{
+		"balance":        {PropertyType: bigNumberType},
+		"account_number": {PropertyType: bigIntegerType},
+		"reference":      {PropertyType: bigIntegerType},
+		"total":          {PropertyType: bigNumberType},
+	}}}}
+
+	createDataSourceOpts := client.CreateDataSourceOpts{
+		Columns: []*models.WebhookDataSourceColumnInput{
+			{Name: "balance", Type: models.StringPropelType, JsonProperty: "balance"},
+			{Name: "account_number", Type: models.StringPropelType, JsonProperty: "account_number"},
+			{Name: "reference", Type: models.StringPropelType, JsonProperty: "reference"},
+			{Name: "total", Type: models.StringPropelType, JsonProperty: "total"},
+		},
+	}
+
+	a := assert.New(t)
+	a.Equal([]string{"balance", "account_number"}, keepBigNumberColumns(dataSource, configuredStream, createDataSourceOpts))
+	a.Equal(models.DoublePropelType, createDataSourceOpts.Columns[0].Type)
+	a.Equal(models.Int64PropelType, createDataSourceOpts.Columns[1].Type)
+	a.Equal(models.StringPropelType, createDataSourceOpts.Columns[2].Type)
+	a.Equal(models.StringPropelType, createDataSourceOpts.Columns[3].Type)
+}
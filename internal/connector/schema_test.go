@@ -0,0 +1,69 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/propeldata/go-client/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestResolveCatalogSchemas(t *testing.T) {
+	c := require.New(t)
+
+	var configuredCatalog airbyte.ConfiguredCatalog
+	c.NoError(UnmarshalFromPath("./test_files/configured_catalog_refs.json", &configuredCatalog))
+	c.NoError(resolveCatalogSchemas(&configuredCatalog))
+
+	properties := configuredCatalog.Streams[0].Stream.JSONSchema.Properties
+	c.Equal("Order total", properties["total"].Description)
+	c.Equal("string", string(properties["shipping"].Properties["city"].TypeSet.Types[0]))
+	c.Equal([]airbyte.PropType{airbyte.Object}, properties["shipping"].Properties["previous"].TypeSet.Types)
+	c.Nil(properties["shipping"].Properties["previous"].Properties)
+	c.Equal([]airbyte.PropType{airbyte.Null, airbyte.Number}, properties["line_items"].ResolvedItems.Properties["price"].TypeSet.Types)
+
+	tests := []struct {
+		property           string
+		expectedPropelType models.PropelType
+	}{
+		{property: "id", expectedPropelType: models.Int64PropelType},
+		{property: "total", expectedPropelType: models.DoublePropelType},
+		{property: "shipping", expectedPropelType: models.JsonPropelType},
+		{property: "created_at", expectedPropelType: models.TimestampPropelType},
+		{property: "quantity", expectedPropelType: models.DoublePropelType},
+		{property: "updated_at", expectedPropelType: models.TimestampPropelType},
+		{property: "reference", expectedPropelType: models.StringPropelType},
+		{property: "anything", expectedPropelType: models.StringPropelType},
+		{property: "line_items", expectedPropelType: models.JsonPropelType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.property, func(st *testing.T) {
+			a := assert.New(st)
+
+			propelType, err := ConvertAirbyteTypeToPropelType(properties[tt.property].PropertyType)
+			a.NoError(err)
+			a.Equal(tt.expectedPropelType, propelType)
+		})
+	}
+}
+
+func TestResolveSchema_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		ref           string
+		expectedError string
+	}{
+		{name: "Unknown definition", ref: "#/definitions/missing", expectedError: `property "price": reference "#/definitions/missing" is unknown or not local`},
+		{name: "Remote reference", ref: "https://example.com/schema.json", expectedError: `property "price": reference "https://example.com/schema.json" is unknown or not local`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			_, err := resolveSchema(airbyte.Properties{Properties: map[string]airbyte.PropertySpec{"price": {Ref: tt.ref}}})
+			assert.EqualError(st, err, tt.expectedError)
+		})
+	}
+}
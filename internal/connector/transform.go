@@ -0,0 +1,220 @@
+package connector
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+// Transform protects the values of a column holding personal data.
+type Transform string
+
+const (
+	// TransformHash replaces values by the hexadecimal SHA-256 hash of the PII salt followed by the value.
+	TransformHash Transform = "hash"
+	// TransformMask keeps the last characters of values, or the first character and domain of emails, masking the rest.
+	TransformMask Transform = "mask"
+	// TransformTokenize replaces values by short tokens derived from the PII salt, equal for equal values.
+	TransformTokenize Transform = "tokenize"
+	// TransformDrop leaves the column out of both the Data Source and the records.
+	TransformDrop Transform = "drop"
+)
+
+const (
+	// maskVisibleChars is the number of trailing characters masking keeps visible.
+	maskVisibleChars = 4
+	maskChar         = '*'
+	tokenPrefix      = "tok_"
+	// tokenLength is the number of hexadecimal digits of tokens.
+	tokenLength = 16
+)
+
+var transforms = []Transform{TransformHash, TransformMask, TransformTokenize, TransformDrop}
+
+// transformer applies the PII transforms of a stream to its records.
+type transformer struct {
+	transforms map[string]Transform
+	// paths are the paths of the transformed properties within records, as they are transformed before flattening so
+	// that flattened objects kept as JSON don't hold the original values.
+	paths map[string][]string
+	salt  string
+}
+
+// applyTransforms turns the properties the stream transforms into strings, and removes those it drops. It returns the
+// transformed stream, along with the transformer of its records, which is nil when there are no transforms. Primary
+// key and cursor properties, and flattened objects, can't be transformed, and every transformed property must be one
+// of the stream.
+func applyTransforms(configuredStream airbyte.ConfiguredStream, streamTransforms map[string]Transform, salt string, recordFlattener *flattener) (airbyte.ConfiguredStream, *transformer, error) {
+	if len(streamTransforms) == 0 {
+		return configuredStream, nil, nil
+	}
+
+	schema := configuredStream.Stream.JSONSchema
+	properties := make(map[string]airbyte.PropertySpec, len(schema.Properties))
+	for propertyName, propertySpec := range schema.Properties {
+		properties[propertyName] = propertySpec
+	}
+
+	for propertyName, transform := range streamTransforms {
+		for _, pk := range configuredStream.PrimaryKey {
+			if slices.Equal(pk, []string{propertyName}) {
+				return configuredStream, nil, fmt.Errorf("primary key property %q can't be transformed", propertyName)
+			}
+		}
+
+		if len(configuredStream.CursorField) > 0 && configuredStream.CursorField[0] == propertyName {
+			return configuredStream, nil, fmt.Errorf("cursor property %q can't be transformed", propertyName)
+		}
+
+		if _, ok := properties[propertyName]; !ok {
+			return configuredStream, nil, fmt.Errorf("transformed property %q is not a property of the stream", propertyName)
+		}
+
+		if recordFlattener.isFlattened(propertyName) {
+			return configuredStream, nil, fmt.Errorf("flattened object %q can't be transformed, transform its nested properties instead", propertyName)
+		}
+
+		if transform == TransformDrop {
+			delete(properties, propertyName)
+			continue
+		}
+
+		properties[propertyName] = airbyte.PropertySpec{
+			PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}},
+		}
+	}
+
+	schema.Properties = properties
+	schema.Required = slices.DeleteFunc(slices.Clone(schema.Required), func(propertyName string) bool {
+		return streamTransforms[propertyName] == TransformDrop
+	})
+	configuredStream.Stream.JSONSchema = schema
+
+	paths := make(map[string][]string, len(streamTransforms))
+	for propertyName := range streamTransforms {
+		paths[propertyName] = recordFlattener.path(propertyName)
+	}
+
+	return configuredStream, &transformer{transforms: streamTransforms, paths: paths, salt: salt}, nil
+}
+
+// childTransforms moves the transforms of normalized arrays to their child streams, where the elements of scalar
+// arrays are transformed in the value column. Arrays of objects are transformed by the transforms of their child
+// streams instead. It returns the transforms left to the parent stream, along with those of each child stream.
+func childTransforms(streamTransforms map[string]Transform, children []*arrayChild) (map[string]Transform, map[streamKey]map[string]Transform, error) {
+	parentTransforms := maps.Clone(streamTransforms)
+	arrayTransforms := map[streamKey]map[string]Transform{}
+
+	for _, child := range children {
+		transform, ok := parentTransforms[child.property]
+		if !ok {
+			continue
+		}
+
+		if !child.scalar {
+			return nil, nil, fmt.Errorf("array %q of objects can't be transformed, transform the properties of child stream %q instead", child.property, child.streamName)
+		}
+
+		delete(parentTransforms, child.property)
+		arrayTransforms[child.key] = map[string]Transform{arrayValueColumn: transform}
+	}
+
+	return parentTransforms, arrayTransforms, nil
+}
+
+// transform applies the transforms to the record in place, before it is flattened. Null values are kept, and other
+// values than strings are transformed as their JSON encoding.
+func (t *transformer) transform(recordMap map[string]any) {
+	for propertyName, transform := range t.transforms {
+		object, name := recordMap, propertyName
+		if path, ok := t.paths[propertyName]; ok {
+			// records lacking the object of a nested property get a nil object, which holds nothing
+			parent, _ := lookupPath(recordMap, path[:len(path)-1])
+			object, _ = parent.(map[string]any)
+			name = path[len(path)-1]
+		}
+
+		value, ok := object[name]
+		if !ok {
+			continue
+		}
+
+		if transform == TransformDrop {
+			delete(object, name)
+			continue
+		}
+
+		if value == nil {
+			continue
+		}
+
+		stringValue, ok := value.(string)
+		if !ok {
+			// values decoded from JSON always encode back
+			valueJsonEncoded, _ := json.Marshal(value)
+			stringValue = string(valueJsonEncoded)
+		}
+
+		switch transform {
+		case TransformHash:
+			object[name] = hashValue(stringValue, t.salt)
+		case TransformMask:
+			object[name] = maskValue(stringValue)
+		case TransformTokenize:
+			object[name] = tokenizeValue(stringValue, t.salt)
+		}
+	}
+}
+
+func hashValue(value, salt string) string {
+	hash := sha256.Sum256([]byte(salt + value))
+	return hex.EncodeToString(hash[:])
+}
+
+// maskValue masks every character but the last few. Emails keep the first character of their local part and their
+// domain, e.g. j***@example.com, and values too short to keep anything are masked whole.
+func maskValue(value string) string {
+	if at := strings.LastIndex(value, "@"); at > 0 {
+		local := []rune(value[:at])
+		return string(local[0]) + strings.Repeat(string(maskChar), len(local)-1) + value[at:]
+	}
+
+	runes := []rune(value)
+	if len(runes) <= maskVisibleChars {
+		return strings.Repeat(string(maskChar), len(runes))
+	}
+
+	return strings.Repeat(string(maskChar), len(runes)-maskVisibleChars) + string(runes[len(runes)-maskVisibleChars:])
+}
+
+// tokenizeValue returns a token keyed by the salt, so that tokens can't be recomputed from guessed values without it.
+func tokenizeValue(value, salt string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(value))
+
+	return tokenPrefix + hex.EncodeToString(mac.Sum(nil))[:tokenLength]
+}
+
+// transformedProperties returns the top-level properties of the stream holding transformed values: those of its
+// transformed columns, flattened or not, and the arrays whose child streams transform columns.
+func transformedProperties(dstCfg Config, configuredStream airbyte.ConfiguredStream, streamTransforms map[string]Transform, recordFlattener *flattener, children []*arrayChild) []string {
+	properties := make([]string, 0, len(streamTransforms))
+	for columnName := range streamTransforms {
+		properties = append(properties, recordFlattener.path(columnName)[0])
+	}
+
+	for _, child := range children {
+		if len(dstCfg.streamConfig(getStreamName(configuredStream.Stream.Namespace, child.streamName)).Transforms) > 0 {
+			properties = append(properties, child.property)
+		}
+	}
+
+	return properties
+}
@@ -0,0 +1,165 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestMaskValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "Email", value: "jane@example.com", expected: "j***@example.com"},
+		{name: "Phone number", value: "+1 555 0100", expected: "*******0100"},
+		{name: "Short value", value: "1234", expected: "****"},
+		{name: "Multi-byte characters", value: "señorita", expected: "****rita"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expected, maskValue(tt.value))
+		})
+	}
+}
+
+func TestTransformer_Transform(t *testing.T) {
+	a := assert.New(t)
+
+	tr := &transformer{
+		transforms: map[string]Transform{
+			"email":    TransformHash,
+			"phone":    TransformMask,
+			"customer": TransformTokenize,
+			"ssn":      TransformDrop,
+			"zip":      TransformHash,
+			"notes":    TransformMask,
+		},
+		salt: "pepper",
+	}
+
+	record := map[string]any{"id": float64(1), "email": "jane@example.com", "phone": "5550100", "customer": "jane", "ssn": "123-45-6789", "zip": float64(94107), "notes": nil}
+	tr.transform(record)
+
+	a.Equal(map[string]any{
+		"id":       float64(1),
+		"email":    hashValue("jane@example.com", "pepper"),
+		"phone":    "***0100",
+		"customer": tokenizeValue("jane", "pepper"),
+		"zip":      hashValue("94107", "pepper"),
+		"notes":    nil,
+	}, record)
+	a.Len(record["email"], 64)
+	a.NotEqual(hashValue("jane@example.com", "salt"), record["email"])
+	a.Regexp(`^tok_[0-9a-f]{16}$`, record["customer"])
+}
+
+func TestApplyTransforms(t *testing.T) {
+	c := require.New(t)
+
+	configuredStream := airbyte.ConfiguredStream{
+		PrimaryKey: [][]string{{"id"}},
+		Stream: airbyte.Stream{
+			JSONSchema: airbyte.Properties{
+				Properties: map[string]airbyte.PropertySpec{
+					"id":    {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Integer}}}},
+					"phone": {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Integer}}}},
+					"ssn":   {PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}}},
+				},
+				Required: []string{"id", "ssn"},
+			},
+		},
+	}
+
+	transformed, tr, err := applyTransforms(configuredStream, map[string]Transform{"phone": TransformMask, "ssn": TransformDrop}, "", nil)
+	c.NoError(err)
+	c.NotNil(tr)
+	c.Equal([]airbyte.PropType{airbyte.String}, transformed.Stream.JSONSchema.Properties["phone"].TypeSet.Types)
+	c.NotContains(transformed.Stream.JSONSchema.Properties, "ssn")
+	c.Equal([]string{"id"}, transformed.Stream.JSONSchema.Required)
+	c.Contains(configuredStream.Stream.JSONSchema.Properties, "ssn", "the configured stream is left untouched")
+
+	_, _, err = applyTransforms(configuredStream, map[string]Transform{"id": TransformHash}, "pepper", nil)
+	c.EqualError(err, `primary key property "id" can't be transformed`)
+
+	_, _, err = applyTransforms(configuredStream, map[string]Transform{"email": TransformHash}, "pepper", nil)
+	c.EqualError(err, `transformed property "email" is not a property of the stream`)
+}
+
+func TestApplyTransforms_FlattenedObjects(t *testing.T) {
+	c := require.New(t)
+
+	schema, recordFlattener, err := flattenSchema(airbyte.Properties{
+		Properties: map[string]airbyte.PropertySpec{
+			"id":      stringSpec(),
+			"contact": objectSpec(map[string]airbyte.PropertySpec{"email": stringSpec(), "city": stringSpec()}),
+		},
+	}, StreamConfig{FlattenDepth: 1, FlattenSeparator: "_", KeepFlattenedObjects: true})
+	c.NoError(err)
+
+	configuredStream := airbyte.ConfiguredStream{Stream: airbyte.Stream{JSONSchema: schema}}
+
+	_, _, err = applyTransforms(configuredStream, map[string]Transform{"contact": TransformHash}, "pepper", recordFlattener)
+	c.EqualError(err, `flattened object "contact" can't be transformed, transform its nested properties instead`)
+
+	_, tr, err := applyTransforms(configuredStream, map[string]Transform{"contact_email": TransformHash}, "pepper", recordFlattener)
+	c.NoError(err)
+
+	record := map[string]any{"id": "1", "contact": map[string]any{"email": "jane@example.com", "city": "Lima"}}
+	tr.transform(record)
+	recordFlattener.flattenRecord(record)
+
+	c.Equal(map[string]any{
+		"id":            "1",
+		"contact":       map[string]any{"email": hashValue("jane@example.com", "pepper"), "city": "Lima"},
+		"contact_email": hashValue("jane@example.com", "pepper"),
+		"contact_city":  "Lima",
+	}, record)
+
+	record = map[string]any{"id": "2", "contact": nil}
+	tr.transform(record)
+	c.Equal(map[string]any{"id": "2", "contact": nil}, record)
+}
+
+func TestChildTransforms(t *testing.T) {
+	c := require.New(t)
+
+	children := []*arrayChild{
+		{property: "tags", streamName: "orders__tags", key: streamKey{name: "orders__tags"}, scalar: true},
+		{property: "line_items", streamName: "orders__line_items", key: streamKey{name: "orders__line_items"}},
+	}
+	streamTransforms := map[string]Transform{"tags": TransformMask, "email": TransformHash}
+
+	parentTransforms, arrayTransforms, err := childTransforms(streamTransforms, children)
+	c.NoError(err)
+	c.Equal(map[string]Transform{"email": TransformHash}, parentTransforms)
+	c.Equal(map[streamKey]map[string]Transform{{name: "orders__tags"}: {arrayValueColumn: TransformMask}}, arrayTransforms)
+	c.Len(streamTransforms, 2, "the stream transforms are left untouched")
+
+	_, _, err = childTransforms(map[string]Transform{"line_items": TransformHash}, children)
+	c.EqualError(err, `array "line_items" of objects can't be transformed, transform the properties of child stream "orders__line_items" instead`)
+}
+
+func TestTransformedProperties(t *testing.T) {
+	c := require.New(t)
+
+	_, recordFlattener, err := flattenSchema(airbyte.Properties{
+		Properties: map[string]airbyte.PropertySpec{
+			"id":      stringSpec(),
+			"contact": objectSpec(map[string]airbyte.PropertySpec{"email": stringSpec()}),
+		},
+	}, StreamConfig{FlattenDepth: 1, FlattenSeparator: "_"})
+	c.NoError(err)
+
+	dstCfg := Config{Streams: map[string]StreamConfig{"orders__line_items": {Transforms: map[string]Transform{"sku": TransformMask}}}}
+	children := []*arrayChild{{property: "line_items", streamName: "orders__line_items"}, {property: "tags", streamName: "orders__tags"}}
+	configuredStream := airbyte.ConfiguredStream{Stream: airbyte.Stream{Name: "orders"}}
+
+	properties := transformedProperties(dstCfg, configuredStream, map[string]Transform{"contact_email": TransformHash}, recordFlattener, children)
+	c.Equal([]string{"contact", "line_items"}, properties)
+}
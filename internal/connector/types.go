@@ -2,6 +2,7 @@ package connector
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/propeldata/go-client/models"
 
@@ -9,6 +10,24 @@ import (
 )
 
 func ConvertAirbyteTypeToPropelType(airbyteProperty airbyte.PropertyType) (models.PropelType, error) {
+	if len(airbyteProperty.Union) > 0 {
+		propelTypes := make([]models.PropelType, 0, len(airbyteProperty.Union))
+		for _, branchType := range airbyteProperty.Union {
+			if branchType.TypeSet != nil && len(removeNullType(branchType.TypeSet.Types)) == 0 {
+				continue
+			}
+
+			propelType, err := ConvertAirbyteTypeToPropelType(branchType)
+			if err != nil {
+				return models.PropelType{}, err
+			}
+
+			propelTypes = append(propelTypes, propelType)
+		}
+
+		return commonPropelType(propelTypes), nil
+	}
+
 	if airbyteProperty.TypeSet == nil {
 		// if no general type is specified, default to string
 		return models.StringPropelType, nil
@@ -21,7 +40,33 @@ func ConvertAirbyteTypeToPropelType(airbyteProperty airbyte.PropertyType) (model
 	}
 
 	if len(types) > 1 {
-		// if field may have different types, default to string
+		// if field may have different types, pick the one holding all of them
+		propelTypes := make([]models.PropelType, 0, len(types))
+		for _, propType := range types {
+			propelType, err := ConvertAirbyteTypeToPropelType(airbyte.PropertyType{
+				TypeSet:     &airbyte.PropTypes{Types: []airbyte.PropType{propType}},
+				AirbyteType: airbyteProperty.AirbyteType,
+				Format:      airbyteProperty.Format,
+			})
+			if err != nil {
+				return models.StringPropelType, nil
+			}
+
+			propelTypes = append(propelTypes, propelType)
+		}
+
+		return commonPropelType(propelTypes), nil
+	}
+
+	switch airbyteProperty.AirbyteType {
+	case airbyte.BigInteger, airbyte.BigNumber:
+		// Propel has no decimal type, so arbitrary precision numbers are kept as strings to preserve every digit
+		return models.StringPropelType, nil
+	case airbyte.AirbyteTypeInteger:
+		return models.Int64PropelType, nil
+	case airbyte.TimestampWithTZ, airbyte.TimestampWOTZ:
+		return models.TimestampPropelType, nil
+	case airbyte.TimeWithTZ, airbyte.TimeWOTZ:
 		return models.StringPropelType, nil
 	}
 
@@ -49,6 +94,161 @@ func ConvertAirbyteTypeToPropelType(airbyteProperty airbyte.PropertyType) (model
 	}
 }
 
+// commonPropelType returns the type of a column holding the values of every given type: the type they all share,
+// else the numeric or temporal type holding the others, and STRING otherwise.
+func commonPropelType(propelTypes []models.PropelType) models.PropelType {
+	if len(propelTypes) == 0 {
+		return models.StringPropelType
+	}
+
+	for _, candidate := range propelTypes {
+		if candidate == models.StringPropelType || candidate == models.JsonPropelType {
+			continue
+		}
+
+		holdsAll := true
+		for _, propelType := range propelTypes {
+			// properties that are either integers or numbers are numbers, even though doubles round big integers
+			numeric := candidate == models.DoublePropelType && propelType == models.Int64PropelType
+			holdsAll = holdsAll && (numeric || canHoldPropelType(candidate, propelType))
+		}
+
+		if holdsAll {
+			return candidate
+		}
+	}
+
+	for _, propelType := range propelTypes {
+		if propelType != propelTypes[0] {
+			return models.StringPropelType
+		}
+	}
+
+	return propelTypes[0]
+}
+
+// isTimeOfDay reports whether the property holds times of day without a date.
+func isTimeOfDay(airbyteProperty airbyte.PropertyType) bool {
+	switch airbyteProperty.AirbyteType {
+	case airbyte.TimeWithTZ, airbyte.TimeWOTZ:
+		return true
+	case "":
+		return airbyteProperty.Format == airbyte.Time
+	}
+
+	return false
+}
+
+const (
+	// maxFloatSteps bounds the distinct values a FLOAT column is used for, as 32-bit floats keep 6 significant digits.
+	maxFloatSteps = 1e6
+	// maxInt64Digits is the longest big integer string, sign included, that always fits in INT64.
+	maxInt64Digits = 18
+)
+
+// narrowPropelType returns a narrower type than the column type when the JSON Schema constraints of the property
+// guarantee every value fits in it. Propel has no unsigned integer types, so non-negative ranges get signed types.
+func narrowPropelType(columnType models.PropelType, propertySpec airbyte.PropertySpec) models.PropelType {
+	minimum, maximum, bounded := propertyBounds(propertySpec)
+
+	switch columnType {
+	case models.Int64PropelType:
+		if bounded {
+			return narrowIntegerType(minimum, maximum, columnType)
+		}
+	case models.DoublePropelType:
+		if !bounded || propertySpec.MultipleOf == nil || *propertySpec.MultipleOf <= 0 {
+			return columnType
+		}
+
+		multipleOf := *propertySpec.MultipleOf
+		if multipleOf >= 1 && multipleOf == math.Trunc(multipleOf) {
+			return narrowIntegerType(minimum, maximum, columnType)
+		}
+
+		if math.Max(math.Abs(minimum), math.Abs(maximum))/multipleOf < maxFloatSteps {
+			return models.FloatPropelType
+		}
+	case models.StringPropelType:
+		if propertySpec.AirbyteType != airbyte.BigInteger {
+			return columnType
+		}
+
+		if bounded {
+			return narrowIntegerType(minimum, maximum, columnType)
+		}
+
+		if propertySpec.MaxLength != nil && *propertySpec.MaxLength <= maxInt64Digits {
+			return models.Int64PropelType
+		}
+	}
+
+	return columnType
+}
+
+// propertyBounds returns the range of values of the property, from its minimum and maximum or its numeric enum.
+func propertyBounds(propertySpec airbyte.PropertySpec) (float64, float64, bool) {
+	if propertySpec.Minimum != nil && propertySpec.Maximum != nil {
+		return *propertySpec.Minimum, *propertySpec.Maximum, true
+	}
+
+	if len(propertySpec.Enum) == 0 {
+		return 0, 0, false
+	}
+
+	minimum, maximum := math.Inf(1), math.Inf(-1)
+	for _, value := range propertySpec.Enum {
+		number, ok := value.(float64)
+		if !ok {
+			return 0, 0, false
+		}
+
+		minimum = math.Min(minimum, number)
+		maximum = math.Max(maximum, number)
+	}
+
+	return minimum, maximum, true
+}
+
+// narrowIntegerType returns the narrowest integer type holding the range, or the fallback type when none does.
+func narrowIntegerType(minimum, maximum float64, fallback models.PropelType) models.PropelType {
+	for _, integerType := range []models.PropelType{models.Int8PropelType, models.Int16PropelType, models.Int32PropelType, models.Int64PropelType} {
+		bounds := integerRanges[integerType]
+		// the upper bound is checked against -min, as the max of INT64 rounds up to 2^63 once converted to float64
+		if minimum >= bounds[0] && maximum < -bounds[0] {
+			return integerType
+		}
+	}
+
+	return fallback
+}
+
+// isBigNumber reports whether the property holds arbitrary precision numbers.
+func isBigNumber(airbyteProperty airbyte.PropertyType) bool {
+	return airbyteProperty.AirbyteType == airbyte.BigInteger || airbyteProperty.AirbyteType == airbyte.BigNumber
+}
+
+// acceptsNull reports whether values of the property type may be null, which untyped properties allow.
+func acceptsNull(airbyteProperty airbyte.PropertyType) bool {
+	if len(airbyteProperty.Union) > 0 {
+		for _, branchType := range airbyteProperty.Union {
+			if acceptsNull(branchType) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if airbyteProperty.TypeSet == nil {
+		return true
+	}
+
+	types := removeNullType(airbyteProperty.TypeSet.Types)
+
+	return len(types) == 0 || len(types) != len(airbyteProperty.TypeSet.Types)
+}
+
 func removeNullType(input []airbyte.PropType) []airbyte.PropType {
 	result := make([]airbyte.PropType, 0, len(input))
 
@@ -60,3 +260,30 @@ func removeNullType(input []airbyte.PropType) []airbyte.PropType {
 
 	return result
 }
+
+// propelTypeWidenings lists, for each column type, the narrower types whose values it can hold without loss. Doubles
+// hold integers exactly only up to 2^53, so they can't hold INT64 values.
+var propelTypeWidenings = map[models.PropelType][]models.PropelType{
+	models.Int16PropelType:     {models.Int8PropelType},
+	models.Int32PropelType:     {models.Int8PropelType, models.Int16PropelType},
+	models.Int64PropelType:     {models.Int8PropelType, models.Int16PropelType, models.Int32PropelType},
+	models.FloatPropelType:     {models.Int8PropelType, models.Int16PropelType},
+	models.DoublePropelType:    {models.Int8PropelType, models.Int16PropelType, models.Int32PropelType, models.FloatPropelType},
+	models.TimestampPropelType: {models.DatePropelType},
+}
+
+// canHoldPropelType reports whether a column of the given type can store values of another Propel type.
+// String and JSON columns can hold any value.
+func canHoldPropelType(columnType, valueType models.PropelType) bool {
+	if columnType == valueType || columnType == models.StringPropelType || columnType == models.JsonPropelType {
+		return true
+	}
+
+	for _, narrower := range propelTypeWidenings[columnType] {
+		if narrower == valueType {
+			return true
+		}
+	}
+
+	return false
+}
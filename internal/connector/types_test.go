@@ -14,6 +14,7 @@ func TestConvertAirbyteTypeToPropelType(t *testing.T) {
 		name               string
 		propTypes          []airbyte.PropType
 		format             airbyte.FormatType
+		airbyteType        airbyte.AirbytePropType
 		expectedPropelType models.PropelType
 		expectedError      string
 	}{
@@ -29,6 +30,21 @@ func TestConvertAirbyteTypeToPropelType(t *testing.T) {
 			expectedPropelType: models.StringPropelType,
 			expectedError:      "",
 		},
+		{
+			name:               "Integer and number types",
+			propTypes:          []airbyte.PropType{airbyte.Null, airbyte.Integer, airbyte.Number},
+			expectedPropelType: models.DoublePropelType,
+		},
+		{
+			name:               "Object and array types",
+			propTypes:          []airbyte.PropType{airbyte.Object, airbyte.Array},
+			expectedPropelType: models.JsonPropelType,
+		},
+		{
+			name:               "Multiple types with an unexpected one",
+			propTypes:          []airbyte.PropType{airbyte.Integer, airbyte.PropType("unexpected")},
+			expectedPropelType: models.StringPropelType,
+		},
 		{
 			name:               "Date type",
 			propTypes:          []airbyte.PropType{airbyte.Null, airbyte.String},
@@ -42,6 +58,99 @@ func TestConvertAirbyteTypeToPropelType(t *testing.T) {
 			expectedPropelType: models.BooleanPropelType,
 			expectedError:      "",
 		},
+		{
+			name:               "String type",
+			propTypes:          []airbyte.PropType{airbyte.String},
+			expectedPropelType: models.StringPropelType,
+		},
+		{
+			name:               "Date format",
+			propTypes:          []airbyte.PropType{airbyte.String},
+			format:             airbyte.Date,
+			expectedPropelType: models.DatePropelType,
+		},
+		{
+			name:               "Time format",
+			propTypes:          []airbyte.PropType{airbyte.String},
+			format:             airbyte.Time,
+			expectedPropelType: models.StringPropelType,
+		},
+		{
+			name:               "Number type",
+			propTypes:          []airbyte.PropType{airbyte.Number},
+			expectedPropelType: models.DoublePropelType,
+		},
+		{
+			name:               "Integer type",
+			propTypes:          []airbyte.PropType{airbyte.Integer},
+			expectedPropelType: models.Int64PropelType,
+		},
+		{
+			name:               "Object type",
+			propTypes:          []airbyte.PropType{airbyte.Object},
+			expectedPropelType: models.JsonPropelType,
+		},
+		{
+			name:               "Array type",
+			propTypes:          []airbyte.PropType{airbyte.Null, airbyte.Array},
+			expectedPropelType: models.JsonPropelType,
+		},
+		{
+			name:               "Integer Airbyte type on number",
+			propTypes:          []airbyte.PropType{airbyte.Number},
+			airbyteType:        airbyte.AirbyteTypeInteger,
+			expectedPropelType: models.Int64PropelType,
+		},
+		{
+			name:               "Big integer Airbyte type",
+			propTypes:          []airbyte.PropType{airbyte.Integer},
+			airbyteType:        airbyte.BigInteger,
+			expectedPropelType: models.StringPropelType,
+		},
+		{
+			name:               "Big integer Airbyte type on string",
+			propTypes:          []airbyte.PropType{airbyte.String},
+			airbyteType:        airbyte.BigInteger,
+			expectedPropelType: models.StringPropelType,
+		},
+		{
+			name:               "Big number Airbyte type",
+			propTypes:          []airbyte.PropType{airbyte.Null, airbyte.Number},
+			airbyteType:        airbyte.BigNumber,
+			expectedPropelType: models.StringPropelType,
+		},
+		{
+			name:               "Timestamp with timezone Airbyte type",
+			propTypes:          []airbyte.PropType{airbyte.String},
+			format:             airbyte.DateTime,
+			airbyteType:        airbyte.TimestampWithTZ,
+			expectedPropelType: models.TimestampPropelType,
+		},
+		{
+			name:               "Timestamp without timezone Airbyte type",
+			propTypes:          []airbyte.PropType{airbyte.String},
+			airbyteType:        airbyte.TimestampWOTZ,
+			expectedPropelType: models.TimestampPropelType,
+		},
+		{
+			name:               "Time with timezone Airbyte type",
+			propTypes:          []airbyte.PropType{airbyte.String},
+			format:             airbyte.Time,
+			airbyteType:        airbyte.TimeWithTZ,
+			expectedPropelType: models.StringPropelType,
+		},
+		{
+			name:               "Time without timezone Airbyte type",
+			propTypes:          []airbyte.PropType{airbyte.String},
+			airbyteType:        airbyte.TimeWOTZ,
+			expectedPropelType: models.StringPropelType,
+		},
+		{
+			name:               "Airbyte type on multiple types",
+			propTypes:          []airbyte.PropType{airbyte.String, airbyte.Number},
+			airbyteType:        airbyte.BigNumber,
+			expectedPropelType: models.StringPropelType,
+		},
 		{
 			name:               "Unexpected Airbyte type",
 			propTypes:          []airbyte.PropType{airbyte.PropType("unexpected")},
@@ -55,8 +164,9 @@ func TestConvertAirbyteTypeToPropelType(t *testing.T) {
 			a := assert.New(st)
 
 			propelType, err := ConvertAirbyteTypeToPropelType(airbyte.PropertyType{
-				TypeSet: &airbyte.PropTypes{Types: tt.propTypes},
-				Format:  tt.format,
+				TypeSet:     &airbyte.PropTypes{Types: tt.propTypes},
+				Format:      tt.format,
+				AirbyteType: tt.airbyteType,
 			})
 			a.Equal(tt.expectedPropelType, propelType)
 
@@ -68,3 +178,190 @@ func TestConvertAirbyteTypeToPropelType(t *testing.T) {
 		})
 	}
 }
+
+func TestAcceptsNull(t *testing.T) {
+	tests := []struct {
+		name         string
+		propertyType airbyte.PropertyType
+		expected     bool
+	}{
+		{name: "Non-nullable type", propertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}}, expected: false},
+		{name: "Nullable type", propertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Null, airbyte.String}}}, expected: true},
+		{name: "Untyped", propertyType: airbyte.PropertyType{}, expected: true},
+		{
+			name: "Union with a null branch",
+			propertyType: airbyte.PropertyType{Union: []airbyte.PropertyType{
+				{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}},
+				{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Null}}},
+			}},
+			expected: true,
+		},
+		{
+			name: "Union without a null branch",
+			propertyType: airbyte.PropertyType{Union: []airbyte.PropertyType{
+				{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}},
+				{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.Integer}}},
+			}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expected, acceptsNull(tt.propertyType))
+		})
+	}
+}
+
+func TestIsTimeOfDay(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      airbyte.FormatType
+		airbyteType airbyte.AirbytePropType
+		expected    bool
+	}{
+		{name: "Time format", format: airbyte.Time, expected: true},
+		{name: "Time with timezone", airbyteType: airbyte.TimeWithTZ, expected: true},
+		{name: "Time without timezone", format: airbyte.Time, airbyteType: airbyte.TimeWOTZ, expected: true},
+		{name: "Timestamp", format: airbyte.DateTime, airbyteType: airbyte.TimestampWOTZ, expected: false},
+		{name: "Plain string", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expected, isTimeOfDay(airbyte.PropertyType{
+				TypeSet:     &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}},
+				Format:      tt.format,
+				AirbyteType: tt.airbyteType,
+			}))
+		})
+	}
+}
+
+func TestNarrowPropelType(t *testing.T) {
+	tests := []struct {
+		name               string
+		columnType         models.PropelType
+		propertySpec       airbyte.PropertySpec
+		expectedPropelType models.PropelType
+	}{
+		{
+			name:               "Unbounded integer",
+			columnType:         models.Int64PropelType,
+			propertySpec:       airbyte.PropertySpec{Minimum: ptr(0.0)},
+			expectedPropelType: models.Int64PropelType,
+		},
+		{
+			name:               "INT8 range",
+			columnType:         models.Int64PropelType,
+			propertySpec:       airbyte.PropertySpec{Minimum: ptr(-128.0), Maximum: ptr(127.0)},
+			expectedPropelType: models.Int8PropelType,
+		},
+		{
+			name:               "Non-negative range beyond INT8",
+			columnType:         models.Int64PropelType,
+			propertySpec:       airbyte.PropertySpec{Minimum: ptr(0.0), Maximum: ptr(255.0)},
+			expectedPropelType: models.Int16PropelType,
+		},
+		{
+			name:               "INT32 range",
+			columnType:         models.Int64PropelType,
+			propertySpec:       airbyte.PropertySpec{Minimum: ptr(0.0), Maximum: ptr(86399.0)},
+			expectedPropelType: models.Int32PropelType,
+		},
+		{
+			name:               "Integer enum",
+			columnType:         models.Int64PropelType,
+			propertySpec:       airbyte.PropertySpec{Enum: []any{float64(1), float64(5), float64(1000)}},
+			expectedPropelType: models.Int16PropelType,
+		},
+		{
+			name:               "Mixed enum",
+			columnType:         models.Int64PropelType,
+			propertySpec:       airbyte.PropertySpec{Enum: []any{float64(1), "two"}},
+			expectedPropelType: models.Int64PropelType,
+		},
+		{
+			name:               "Number without multipleOf",
+			columnType:         models.DoublePropelType,
+			propertySpec:       airbyte.PropertySpec{Minimum: ptr(0.0), Maximum: ptr(100.0)},
+			expectedPropelType: models.DoublePropelType,
+		},
+		{
+			name:               "Integral multipleOf number",
+			columnType:         models.DoublePropelType,
+			propertySpec:       airbyte.PropertySpec{Minimum: ptr(0.0), Maximum: ptr(100.0), MultipleOf: ptr(5.0)},
+			expectedPropelType: models.Int8PropelType,
+		},
+		{
+			name:               "Integral multipleOf number out of INT64 range",
+			columnType:         models.DoublePropelType,
+			propertySpec:       airbyte.PropertySpec{Minimum: ptr(0.0), Maximum: ptr(1e20), MultipleOf: ptr(1.0)},
+			expectedPropelType: models.DoublePropelType,
+		},
+		{
+			name:               "Few decimal digits",
+			columnType:         models.DoublePropelType,
+			propertySpec:       airbyte.PropertySpec{Minimum: ptr(0.0), Maximum: ptr(1000.0), MultipleOf: ptr(0.01)},
+			expectedPropelType: models.FloatPropelType,
+		},
+		{
+			name:               "Too many decimal digits",
+			columnType:         models.DoublePropelType,
+			propertySpec:       airbyte.PropertySpec{Minimum: ptr(0.0), Maximum: ptr(1e6), MultipleOf: ptr(0.01)},
+			expectedPropelType: models.DoublePropelType,
+		},
+		{
+			name:               "Big integer with maxLength",
+			columnType:         models.StringPropelType,
+			propertySpec:       airbyte.PropertySpec{PropertyType: airbyte.PropertyType{AirbyteType: airbyte.BigInteger}, MaxLength: ptr(18)},
+			expectedPropelType: models.Int64PropelType,
+		},
+		{
+			name:               "Big integer with long maxLength",
+			columnType:         models.StringPropelType,
+			propertySpec:       airbyte.PropertySpec{PropertyType: airbyte.PropertyType{AirbyteType: airbyte.BigInteger}, MaxLength: ptr(19)},
+			expectedPropelType: models.StringPropelType,
+		},
+		{
+			name:               "Big integer with bounds",
+			columnType:         models.StringPropelType,
+			propertySpec:       airbyte.PropertySpec{PropertyType: airbyte.PropertyType{AirbyteType: airbyte.BigInteger}, Minimum: ptr(0.0), Maximum: ptr(1e9)},
+			expectedPropelType: models.Int32PropelType,
+		},
+		{
+			name:               "Plain string with maxLength",
+			columnType:         models.StringPropelType,
+			propertySpec:       airbyte.PropertySpec{MaxLength: ptr(2)},
+			expectedPropelType: models.StringPropelType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expectedPropelType, narrowPropelType(tt.columnType, tt.propertySpec))
+		})
+	}
+}
+
+func TestCommonPropelType(t *testing.T) {
+	tests := []struct {
+		name               string
+		propelTypes        []models.PropelType
+		expectedPropelType models.PropelType
+	}{
+		{name: "No types", expectedPropelType: models.StringPropelType},
+		{name: "Same types", propelTypes: []models.PropelType{models.JsonPropelType, models.JsonPropelType}, expectedPropelType: models.JsonPropelType},
+		{name: "Integers", propelTypes: []models.PropelType{models.Int8PropelType, models.Int32PropelType}, expectedPropelType: models.Int32PropelType},
+		{name: "Integer and double", propelTypes: []models.PropelType{models.Int64PropelType, models.DoublePropelType}, expectedPropelType: models.DoublePropelType},
+		{name: "Date and timestamp", propelTypes: []models.PropelType{models.DatePropelType, models.TimestampPropelType}, expectedPropelType: models.TimestampPropelType},
+		{name: "Boolean and integer", propelTypes: []models.PropelType{models.BooleanPropelType, models.Int64PropelType}, expectedPropelType: models.StringPropelType},
+		{name: "JSON and integer", propelTypes: []models.PropelType{models.JsonPropelType, models.Int64PropelType}, expectedPropelType: models.StringPropelType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			assert.Equal(st, tt.expectedPropelType, commonPropelType(tt.propelTypes))
+		})
+	}
+}
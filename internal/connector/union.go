@@ -0,0 +1,180 @@
+package connector
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+// defaultDiscriminatorColumn records the stream each record of a union comes from.
+const defaultDiscriminatorColumn = "_airbyte_source_stream"
+
+// UnionConfig writes several streams, such as the shards of a table, to a single Data Source.
+type UnionConfig struct {
+	Streams             []string `json:"streams"`
+	DiscriminatorColumn string   `json:"discriminator_column,omitempty"`
+}
+
+// discriminatorColumn returns the column recording the stream of each record.
+func (uc UnionConfig) discriminatorColumn() string {
+	if uc.DiscriminatorColumn == "" {
+		return defaultDiscriminatorColumn
+	}
+
+	return uc.DiscriminatorColumn
+}
+
+// unionStreams replaces the streams of each union by a single stream named after the union, whose schema holds the
+// properties of all of them along with the discriminator column. It returns the streams to write, along with the key of
+// the union stream of each stream that was merged into one. Streams of a union must share their sync mode, primary
+// key and cursor, and de-duplicating unions add the discriminator column to their primary key, so that records of
+// different streams never replace each other.
+func unionStreams(configuredStreams []airbyte.ConfiguredStream, unions map[string]UnionConfig) ([]airbyte.ConfiguredStream, map[streamKey]streamKey, error) {
+	if len(unions) == 0 {
+		return configuredStreams, map[streamKey]streamKey{}, nil
+	}
+
+	unionNames := make([]string, 0, len(unions))
+	for unionName := range unions {
+		unionNames = append(unionNames, unionName)
+	}
+	slices.Sort(unionNames)
+
+	members := map[string][]airbyte.ConfiguredStream{}
+	merged := make([]airbyte.ConfiguredStream, 0, len(configuredStreams))
+	unionKeys := map[streamKey]streamKey{}
+
+	for _, configuredStream := range configuredStreams {
+		streamName := getStreamName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)
+
+		unionName := ""
+		for _, name := range unionNames {
+			if slices.Contains(unions[name].Streams, streamName) {
+				unionName = name
+				break
+			}
+		}
+
+		if unionName == "" {
+			merged = append(merged, configuredStream)
+			continue
+		}
+
+		members[unionName] = append(members[unionName], configuredStream)
+		unionKeys[streamKey{namespace: configuredStream.Stream.Namespace, name: configuredStream.Stream.Name}] = streamKey{name: unionName}
+	}
+
+	for _, unionName := range unionNames {
+		for _, configuredStream := range merged {
+			if configuredStream.Stream.Namespace == "" && configuredStream.Stream.Name == unionName {
+				return nil, nil, fmt.Errorf("union %q has the name of stream %q, which it doesn't hold", unionName, unionName)
+			}
+		}
+
+		if len(members[unionName]) == 0 {
+			continue
+		}
+
+		unionStream, err := mergeUnionStreams(unionName, unions[unionName], members[unionName])
+		if err != nil {
+			return nil, nil, fmt.Errorf("union %q: %w", unionName, err)
+		}
+
+		merged = append(merged, unionStream)
+	}
+
+	return merged, unionKeys, nil
+}
+
+// mergeUnionStreams merges the streams of a union into one.
+func mergeUnionStreams(unionName string, union UnionConfig, configuredStreams []airbyte.ConfiguredStream) (airbyte.ConfiguredStream, error) {
+	first := configuredStreams[0]
+	firstName := getStreamName(first.Stream.Namespace, first.Stream.Name)
+	discriminatorColumn := union.discriminatorColumn()
+
+	properties := map[string]airbyte.PropertySpec{}
+	required := slices.Clone(first.Stream.JSONSchema.Required)
+
+	for _, configuredStream := range configuredStreams {
+		streamName := getStreamName(configuredStream.Stream.Namespace, configuredStream.Stream.Name)
+
+		if configuredStream.DestinationSyncMode != first.DestinationSyncMode {
+			return airbyte.ConfiguredStream{}, fmt.Errorf("streams %q and %q have different destination sync modes", firstName, streamName)
+		}
+
+		if !reflect.DeepEqual(configuredStream.PrimaryKey, first.PrimaryKey) {
+			return airbyte.ConfiguredStream{}, fmt.Errorf("streams %q and %q have different primary keys", firstName, streamName)
+		}
+
+		if !slices.Equal(configuredStream.CursorField, first.CursorField) {
+			return airbyte.ConfiguredStream{}, fmt.Errorf("streams %q and %q have different cursors", firstName, streamName)
+		}
+
+		if _, ok := configuredStream.Stream.JSONSchema.Properties[discriminatorColumn]; ok {
+			return airbyte.ConfiguredStream{}, fmt.Errorf("discriminator column %q collides with a property of stream %q", discriminatorColumn, streamName)
+		}
+
+		for propertyName, propertySpec := range configuredStream.Stream.JSONSchema.Properties {
+			if existing, ok := properties[propertyName]; ok {
+				propertySpec = mergePropertySpecs(existing, propertySpec)
+			}
+
+			properties[propertyName] = propertySpec
+		}
+
+		// properties some streams lack are null in their records
+		required = slices.DeleteFunc(required, func(propertyName string) bool {
+			return !slices.Contains(configuredStream.Stream.JSONSchema.Required, propertyName)
+		})
+	}
+
+	properties[discriminatorColumn] = airbyte.PropertySpec{
+		PropertyType: airbyte.PropertyType{TypeSet: &airbyte.PropTypes{Types: []airbyte.PropType{airbyte.String}}},
+	}
+
+	unionStream := first
+	unionStream.Stream = airbyte.Stream{
+		Name: unionName,
+		JSONSchema: airbyte.Properties{
+			Properties: properties,
+			Required:   append(required, discriminatorColumn),
+		},
+		SupportedSyncModes: first.Stream.SupportedSyncModes,
+	}
+
+	if first.DestinationSyncMode == airbyte.DestinationSyncModeAppendDedup {
+		unionStream.PrimaryKey = append(slices.Clone(first.PrimaryKey), []string{discriminatorColumn})
+	}
+
+	return unionStream, nil
+}
+
+// mergePropertySpecs returns the spec of a property that streams of a union declare differently: it accepts the types
+// of both, and holds the nested properties of both.
+func mergePropertySpecs(a, b airbyte.PropertySpec) airbyte.PropertySpec {
+	if reflect.DeepEqual(a, b) {
+		return a
+	}
+
+	merged := a
+	merged.PropertyType = mergeBranchTypes([]airbyte.PropertySpec{a, b})
+
+	if len(b.Properties) > 0 {
+		merged.Properties = make(map[string]airbyte.PropertySpec, len(a.Properties)+len(b.Properties))
+		for propertyName, propertySpec := range a.Properties {
+			merged.Properties[propertyName] = propertySpec
+		}
+
+		for propertyName, propertySpec := range b.Properties {
+			if existing, ok := merged.Properties[propertyName]; ok {
+				propertySpec = mergePropertySpecs(existing, propertySpec)
+			}
+
+			merged.Properties[propertyName] = propertySpec
+		}
+	}
+
+	return merged
+}
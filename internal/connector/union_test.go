@@ -0,0 +1,99 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/propeldata/airbyte-destination/internal/airbyte"
+)
+
+func TestUnionStreams(t *testing.T) {
+	c := require.New(t)
+
+	var configuredCatalog airbyte.ConfiguredCatalog
+	c.NoError(UnmarshalFromPath("./test_files/configured_catalog.json", &configuredCatalog))
+	catalogStreams := configuredCatalog.Streams
+
+	var unionCatalog airbyte.ConfiguredCatalog
+	c.NoError(UnmarshalFromPath("./test_files/configured_catalog_union.json", &unionCatalog))
+	catalogStreams = append(catalogStreams, unionCatalog.Streams...)
+
+	streams, unionKeys, err := unionStreams(catalogStreams, map[string]UnionConfig{
+		"orders": {Streams: []string{"orders_eu", "orders_us"}},
+	})
+	c.NoError(err)
+	c.Len(streams, len(catalogStreams)-1)
+	c.Equal(map[streamKey]streamKey{
+		{name: "orders_eu"}: {name: "orders"},
+		{name: "orders_us"}: {name: "orders"},
+	}, unionKeys)
+
+	union := streams[len(streams)-1]
+	c.Equal("orders", union.Stream.Name)
+	c.Equal([][]string{{"id"}, {defaultDiscriminatorColumn}}, union.PrimaryKey)
+	c.Equal([]string{"updated_at"}, union.CursorField)
+	c.ElementsMatch([]string{"id", "updated_at", defaultDiscriminatorColumn}, union.Stream.JSONSchema.Required)
+
+	properties := union.Stream.JSONSchema.Properties
+	c.Len(properties, 6)
+	c.Contains(properties, "vat_id")
+	c.Contains(properties, "state")
+	c.ElementsMatch([]airbyte.PropType{airbyte.Integer, airbyte.Number}, properties["amount"].TypeSet.Types)
+	c.Equal([]airbyte.PropType{airbyte.String}, properties[defaultDiscriminatorColumn].TypeSet.Types)
+
+	streams, unionKeys, err = unionStreams(catalogStreams, nil)
+	c.NoError(err)
+	c.Equal(catalogStreams, streams)
+	c.Empty(unionKeys)
+}
+
+func TestUnionStreams_Mismatches(t *testing.T) {
+	var configuredCatalog airbyte.ConfiguredCatalog
+	require.NoError(t, UnmarshalFromPath("./test_files/configured_catalog_union.json", &configuredCatalog))
+
+	tests := []struct {
+		name          string
+		change        func(streams []airbyte.ConfiguredStream)
+		union         UnionConfig
+		expectedError string
+	}{
+		{
+			name: "Different sync modes",
+			change: func(streams []airbyte.ConfiguredStream) {
+				streams[1].DestinationSyncMode = airbyte.DestinationSyncModeAppend
+			},
+			expectedError: `union "orders": streams "orders_eu" and "orders_us" have different destination sync modes`,
+		},
+		{
+			name:          "Different primary keys",
+			change:        func(streams []airbyte.ConfiguredStream) { streams[1].PrimaryKey = [][]string{{"id"}, {"state"}} },
+			expectedError: `union "orders": streams "orders_eu" and "orders_us" have different primary keys`,
+		},
+		{
+			name:          "Different cursors",
+			change:        func(streams []airbyte.ConfiguredStream) { streams[1].CursorField = []string{"id"} },
+			expectedError: `union "orders": streams "orders_eu" and "orders_us" have different cursors`,
+		},
+		{
+			name:          "Discriminator collision",
+			change:        func([]airbyte.ConfiguredStream) {},
+			union:         UnionConfig{DiscriminatorColumn: "state"},
+			expectedError: `union "orders": discriminator column "state" collides with a property of stream "orders_us"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(st *testing.T) {
+			streams := make([]airbyte.ConfiguredStream, len(configuredCatalog.Streams))
+			copy(streams, configuredCatalog.Streams)
+			tt.change(streams)
+
+			union := tt.union
+			union.Streams = []string{"orders_eu", "orders_us"}
+
+			_, _, err := unionStreams(streams, map[string]UnionConfig{"orders": union})
+			require.EqualError(st, err, tt.expectedError)
+		})
+	}
+}